@@ -3,6 +3,8 @@ package main
 import (
 	"machine"
 	"time"
+
+	"github.com/ysoldak/HeadTracker/src/orientation"
 )
 
 type BluetoothCallbackHandler struct {
@@ -20,7 +22,7 @@ func (b *BluetoothCallbackHandler) OnDisconnect() {
 
 func (b *BluetoothCallbackHandler) OnOrientationReset() {
 	println("Orientation reset via Bluetooth command")
-	o.Reset()
+	resetOrientation(state.recenterModes[recenterSourceCommand])
 }
 
 func (b *BluetoothCallbackHandler) OnFactoryReset() {
@@ -37,6 +39,16 @@ func (b *BluetoothCallbackHandler) OnReboot() {
 	machine.CPUReset()
 }
 
+func (b *BluetoothCallbackHandler) OnAccCalibrate() {
+	println("Accelerometer calibration via Bluetooth command")
+	startAccCalibration()
+}
+
+func (b *BluetoothCallbackHandler) OnHoldToggle() {
+	println("Output hold via Bluetooth command")
+	toggleHold()
+}
+
 func (b *BluetoothCallbackHandler) OnDeviceNameChange(name string) {
 	println("Device name changed to", name)
 	state.deviceName = name
@@ -46,3 +58,106 @@ func (b *BluetoothCallbackHandler) OnAxisMappingChange(mapping [3]byte) {
 	println("Axis mapping changed to", mapping[0], mapping[1], mapping[2])
 	state.axisMapping = mapping
 }
+
+func (b *BluetoothCallbackHandler) OnFusionChange(fusion byte) {
+	println("Fusion changed to", fusion)
+	state.fusion = fusion
+	setFusion(fusion)
+}
+
+func (b *BluetoothCallbackHandler) OnMountingChange(mounting [9]byte) {
+	println("Mounting changed to", mounting[0])
+	state.mounting = mounting
+	setMounting(mounting)
+	o.Reset()
+}
+
+func (b *BluetoothCallbackHandler) OnCurvesChange(curves [18]byte) {
+	println("Curves changed")
+	state.curves = curves
+	setCurves(curves, state.gimbal)
+}
+
+func (b *BluetoothCallbackHandler) OnGimbalChange(gimbal [12]byte) {
+	println("Gimbal profile changed, servo throws:", gimbal[0], gimbal[4], gimbal[8])
+	state.gimbal = gimbal
+	setCurves(state.curves, gimbal)
+}
+
+func (b *BluetoothCallbackHandler) OnAnglesChange(angles byte) {
+	println("Angles decomposition changed to", angles)
+	state.angles = angles
+	o.SetDecomposition(orientation.Decomposition(angles))
+}
+
+func (b *BluetoothCallbackHandler) OnAdaptiveChange(adaptive [4]byte) {
+	println("Adaptive gain changed to", adaptive[0], adaptive[1], adaptive[2], adaptive[3])
+	state.adaptive = adaptive
+	setAdaptive(adaptive)
+}
+
+func (b *BluetoothCallbackHandler) OnGesturesChange(gestures [5]byte) {
+	println("Gestures changed to", gestures[0], gestures[1], gestures[2], gestures[3], gestures[4])
+	state.gestures = gestures
+}
+
+func (b *BluetoothCallbackHandler) OnHoldChange(hold [2]byte) {
+	println("Hold mode changed to", hold[0], hold[1])
+	state.hold = hold
+	state.holdOutput.Configure(hold)
+}
+
+func (b *BluetoothCallbackHandler) OnRecenterChange(recenter byte) {
+	println("Re-center duration changed to", recenter)
+	state.recenter = recenter
+	o.SetRecenter(time.Duration(recenter) * 100 * time.Millisecond)
+}
+
+func (b *BluetoothCallbackHandler) OnRecenterModesChange(recenterModes [3]byte) {
+	println("Re-center modes changed to", recenterModes[0], recenterModes[1], recenterModes[2])
+	state.recenterModes = recenterModes
+}
+
+func (b *BluetoothCallbackHandler) OnAutoCenterChange(autoCenter [5]byte) {
+	println("Auto center changed to", autoCenter[0], autoCenter[1], autoCenter[2], autoCenter[3], autoCenter[4])
+	state.autoCenter = autoCenter
+	setAutoCenter(autoCenter)
+}
+
+func (b *BluetoothCallbackHandler) OnPredictionChange(prediction [2]byte) {
+	println("Prediction changed to", prediction[0], prediction[1])
+	state.prediction = prediction
+	setPrediction(prediction)
+}
+
+func (b *BluetoothCallbackHandler) OnFiltersChange(filters [9]byte) {
+	println("Filters changed to", filters[0], filters[FILTER_BYTES], filters[2*FILTER_BYTES])
+	state.filters = filters
+	setFilters(filters)
+}
+
+func (b *BluetoothCallbackHandler) OnPanChange(pan [3]byte) {
+	println("Pan changed to", pan[0], pan[1], pan[2])
+	state.pan = pan
+	setPan(pan)
+}
+
+func (b *BluetoothCallbackHandler) OnPPMChange(ppm [4]byte) {
+	println("PPM output changed to", ppm[0], ppm[1], ppm[2], ppm[3])
+	state.ppm = ppm
+}
+
+func (b *BluetoothCallbackHandler) OnWiredChange(wired byte) {
+	println("Wired output changed to", wired)
+	state.wired = wired
+}
+
+func (b *BluetoothCallbackHandler) OnMavlinkChange(mavlink [6]byte) {
+	println("MAVLink output changed to", mavlink[0], mavlink[1], mavlink[2], mavlink[3], mavlink[4], mavlink[5])
+	state.mavlink = mavlink
+}
+
+func (b *BluetoothCallbackHandler) OnControllerChange(controller [3]byte) {
+	println("Gimbal controller output changed to", controller[0], controller[1], controller[2])
+	state.controller = controller
+}
@@ -0,0 +1,76 @@
+package main
+
+// Per-axis response curves, shape angles before they become channel values.
+//
+// Every axis has 6 bytes:
+// - deadband   0.1 degree units, head angle around center that does not move the output,
+// - expo       percent (0-100), softens output around center, keeps full scale,
+// - gain       degrees (1-180) of head rotation for full scale output (±512us),
+// - endpoint   percent of full scale on negative side (0-100),
+// - endpoint   percent of full scale on positive side (0-100),
+// - trim       int8, microseconds added to center (1500).
+//
+// Default curve "00 00 B4 64 64 00" (no deadband, no expo, 180 degrees, 100% endpoints, no trim)
+// is linear mapping of ±180 degrees onto 988..2012us.
+
+import "math"
+
+const CURVE_BYTES = 6
+
+const (
+	curveCenter = 1500 // us
+	curveScale  = 512  // us, full scale output in standard range (988..2012us)
+)
+
+var defaultCurve = [CURVE_BYTES]byte{0, 0, 180, 100, 100, 0}
+
+type Curve struct {
+	deadband float64 // degrees
+	expo     float64 // 0..1
+	gain     float64 // degrees
+	endLow   float64 // 0..1
+	endHigh  float64 // 0..1
+	trim     float64 // us
+	scale    float64 // us, full scale output, see "Gimbal"
+}
+
+func NewCurve(b [CURVE_BYTES]byte) Curve {
+	c := Curve{
+		deadband: float64(b[0]) / 10,
+		expo:     float64(min(b[1], 100)) / 100,
+		gain:     float64(min(b[2], 180)),
+		endLow:   float64(min(b[3], 100)) / 100,
+		endHigh:  float64(min(b[4], 100)) / 100,
+		trim:     float64(int8(b[5])),
+		scale:    curveScale,
+	}
+	if c.gain == 0 {
+		c.gain = 180
+	}
+	if c.deadband >= c.gain {
+		c.deadband = 0
+	}
+	return c
+}
+
+// Channel value for an angle (radians)
+func (c Curve) Channel(angle float64) uint16 {
+	a := angle * 180 / math.Pi
+
+	// deadband, output starts from zero right at its edge
+	x := math.Max(math.Abs(a)-c.deadband, 0) / (c.gain - c.deadband)
+	x = math.Min(x, 1)
+
+	// expo, same as radios do
+	x = (1-c.expo)*x + c.expo*x*x*x
+
+	// endpoints
+	if a < 0 {
+		x = -x * c.endLow
+	} else {
+		x = x * c.endHigh
+	}
+
+	result := curveCenter + c.trim + x*c.scale
+	return uint16(math.Min(math.Max(result, curveCenter-c.scale), curveCenter+c.scale))
+}
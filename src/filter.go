@@ -0,0 +1,106 @@
+package main
+
+// Per-axis output smoothing, between angles and curves, removes fusion jitter that makes servos buzz at rest.
+//
+// Every axis has 3 bytes: type, then two parameters depending on type:
+// - 0 none,
+// - 1 One-Euro filter, min cutoff (0.1 Hz), beta (0.01 units); smooths a lot at rest, little in motion, so adds almost no lag when it matters
+//   (cutoff is min cutoff plus beta times rate in degrees per second), see https://gery.casiez.net/1euro/
+// - 2 biquad low-pass filter, cutoff (0.1 Hz), Q (0.01 units); same smoothing always, lags in motion.
+//
+// Default "01 0A 14" is One-Euro filter, 1Hz min cutoff, beta 0.2.
+//
+// Angles are unwrapped before filtering, so pan crossing ±180 degrees does not swing the filter across the whole range.
+
+import "math"
+
+const FILTER_BYTES = 3
+
+const (
+	filterNone    = 0
+	filterOneEuro = 1
+	filterBiquad  = 2
+)
+
+const filterDerivativeCutoff = 1.0 // Hz, One-Euro filter smoothing of rate
+
+var defaultFilter = [FILTER_BYTES]byte{filterOneEuro, 10, 20}
+
+type Filter struct {
+	kind byte
+	dt   float64 // s
+
+	// One-Euro
+	minCutoff float64 // Hz
+	beta      float64
+	rate      float64 // degrees per second, smoothed
+
+	// biquad, normalised coefficients
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+
+	primed    bool
+	raw       float64 // radians, last input
+	unwrapped float64 // radians, last input, unwrapped
+	out       float64 // degrees, last output, unwrapped
+}
+
+func NewFilter(b [FILTER_BYTES]byte, dt float64) Filter {
+	f := Filter{kind: b[0], dt: dt}
+	switch f.kind {
+	case filterOneEuro:
+		f.minCutoff = math.Max(float64(b[1]), 1) / 10
+		f.beta = float64(b[2]) / 100
+	case filterBiquad:
+		cutoff := math.Min(math.Max(float64(b[1]), 1)/10, 0.45/dt) // below Nyquist frequency
+		q := math.Max(float64(b[2]), 10) / 100
+		w := 2 * math.Pi * cutoff * dt
+		alpha := math.Sin(w) / (2 * q)
+		a0 := 1 + alpha
+		f.b0 = (1 - math.Cos(w)) / 2 / a0
+		f.b1 = (1 - math.Cos(w)) / a0
+		f.b2 = f.b0
+		f.a1 = -2 * math.Cos(w) / a0
+		f.a2 = (1 - alpha) / a0
+	default:
+		f.kind = filterNone
+	}
+	return f
+}
+
+// Apply filter to an angle (radians)
+func (f *Filter) Apply(angle float64) float64 {
+	if f.kind == filterNone {
+		return angle
+	}
+	if !f.primed {
+		f.raw, f.unwrapped = angle, angle
+		x := angle * 180 / math.Pi
+		f.out = x
+		f.x1, f.x2, f.y1, f.y2 = x, x, x, x
+		f.primed = true
+		return angle
+	}
+	f.unwrapped += math.Remainder(angle-f.raw, 2*math.Pi)
+	f.raw = angle
+	x := f.unwrapped * 180 / math.Pi
+
+	switch f.kind {
+	case filterOneEuro:
+		rate := (x - f.out) / f.dt
+		f.rate += (rate - f.rate) * smoothing(filterDerivativeCutoff, f.dt)
+		cutoff := f.minCutoff + f.beta*math.Abs(f.rate)
+		f.out += (x - f.out) * smoothing(cutoff, f.dt)
+	case filterBiquad:
+		f.out = f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+		f.x2, f.x1 = f.x1, x
+		f.y2, f.y1 = f.y1, f.out
+	}
+	return math.Remainder(f.out*math.Pi/180, 2*math.Pi)
+}
+
+// Share of the difference applied every step of dt for exponential smoothing with given cutoff
+func smoothing(cutoff, dt float64) float64 {
+	tau := 1 / (2 * math.Pi * cutoff)
+	return 1 / (1 + tau/dt)
+}
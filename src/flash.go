@@ -9,30 +9,102 @@ var errFlashWrongChecksum = errors.New("wrong checksum reading data from flash")
 var errFlashWrongLength = errors.New("unsupported flash data length")
 
 const (
-	FLASH_HEADER_BYTES       = 2 // checksum + length
-	FLASH_GYR_CAL_BLOCKS     = 3 // gyro calibration offsets (int32 each)
-	FLASH_GYR_CAL_BYTES      = FLASH_GYR_CAL_BLOCKS * 4
-	FLASH_DEVICE_NAME_BYTES  = 16 // custom device name
-	FLASH_AXIS_MAPPING_BYTES = 3  // axis mapping (3 bytes)
-	FLASH_LENGTH             = FLASH_HEADER_BYTES + FLASH_GYR_CAL_BYTES + FLASH_DEVICE_NAME_BYTES + FLASH_AXIS_MAPPING_BYTES
+	FLASH_HEADER_BYTES         = 2 // checksum + length
+	FLASH_GYR_CAL_BLOCKS       = 3 // gyro calibration offsets (int32 each)
+	FLASH_GYR_CAL_BYTES        = FLASH_GYR_CAL_BLOCKS * 4
+	FLASH_DEVICE_NAME_BYTES    = 16 // custom device name
+	FLASH_AXIS_MAPPING_BYTES   = 3  // axis mapping (3 bytes)
+	FLASH_FUSION_BYTES         = 1  // sensor fusion algorithm
+	FLASH_MAG_CAL_BLOCKS       = 3  // magnetometer calibration offsets and radii (int32 each)
+	FLASH_MAG_CAL_BYTES        = FLASH_MAG_CAL_BLOCKS * 4 * 2
+	FLASH_MOUNTING_BYTES       = 9 // board mounting: orientation index + adjustment quaternion (4 x int16)
+	FLASH_ACC_CAL_BLOCKS       = 3 // accelerometer calibration offsets and scales (int32 each)
+	FLASH_ACC_CAL_BYTES        = FLASH_ACC_CAL_BLOCKS * 4 * 2
+	FLASH_GYR_TEMP_BINS        = 8 // gyro offsets versus temperature model, bins of 3 offsets (int16 each)
+	FLASH_GYR_TEMP_BYTES       = FLASH_GYR_TEMP_BINS * 3 * 2
+	FLASH_CURVES_BYTES         = 3 * CURVE_BYTES  // response curves, per axis
+	FLASH_GIMBAL_BYTES         = 3 * GIMBAL_BYTES // gimbal kinematics profile, per axis
+	FLASH_ANGLES_BYTES         = 1                // angles decomposition
+	FLASH_ADAPTIVE_BYTES       = 4                // adaptive fusion gain: flags, still rate, fast rate, acceleration tolerance
+	FLASH_GESTURES_BYTES       = 5                // head gestures: actions for nod, shake, tilt left and right, spare channel
+	FLASH_HOLD_BYTES           = HOLD_BYTES       // hold mode: mode, blend time
+	FLASH_RECENTER_BYTES       = 1                // re-center duration
+	FLASH_RECENTER_MODES_BYTES = 3                // re-center modes: button, taps, command
+	FLASH_AUTO_CENTER_BYTES    = 5                // auto center: flags, still rate, still time, window, rate
+	FLASH_PREDICTION_BYTES     = 2                // latency compensation: horizon, max angle
+	FLASH_FILTERS_BYTES        = 3 * FILTER_BYTES // output smoothing filters, per axis
+	FLASH_PAN_BYTES            = 3                // continuous pan: mode, turns, rate
+	FLASH_PPM_BYTES            = 4                // PPM output: channels, frame, spacer, polarity
+	FLASH_WIRED_BYTES          = 1                // wired output protocol
+	FLASH_MAVLINK_BYTES        = 6                // MAVLink output: IDs, target IDs, message, baud rate
+	FLASH_CONTROLLER_BYTES     = 3                // gimbal controller output: baud rate, inverted axes, speed
+	FLASH_LENGTH               = FLASH_HEADER_BYTES + FLASH_GYR_CAL_BYTES + FLASH_DEVICE_NAME_BYTES + FLASH_AXIS_MAPPING_BYTES + FLASH_FUSION_BYTES + FLASH_MAG_CAL_BYTES + FLASH_MOUNTING_BYTES + FLASH_ACC_CAL_BYTES + FLASH_GYR_TEMP_BYTES + FLASH_CURVES_BYTES + FLASH_GIMBAL_BYTES + FLASH_ANGLES_BYTES + FLASH_ADAPTIVE_BYTES + FLASH_GESTURES_BYTES + FLASH_HOLD_BYTES + FLASH_RECENTER_BYTES + FLASH_RECENTER_MODES_BYTES + FLASH_AUTO_CENTER_BYTES + FLASH_PREDICTION_BYTES + FLASH_FILTERS_BYTES + FLASH_PAN_BYTES + FLASH_PPM_BYTES + FLASH_WIRED_BYTES + FLASH_MAVLINK_BYTES + FLASH_CONTROLLER_BYTES
 )
 
+const flashGyrTempEmpty = -32768 // bin not learned yet
+
 type Flash struct {
 	checksum      byte
 	length        byte
 	gyrCalOffsets [FLASH_GYR_CAL_BLOCKS]int32
 	deviceName    [FLASH_DEVICE_NAME_BYTES]byte
 	axisMapping   [FLASH_AXIS_MAPPING_BYTES]byte
+	fusion        byte
+	magCalOffsets [FLASH_MAG_CAL_BLOCKS]int32
+	magCalRadii   [FLASH_MAG_CAL_BLOCKS]int32
+	mounting      [FLASH_MOUNTING_BYTES]byte
+	accCalOffsets [FLASH_ACC_CAL_BLOCKS]int32
+	accCalScales  [FLASH_ACC_CAL_BLOCKS]int32
+	gyrTempModel  [FLASH_GYR_TEMP_BINS][3]int16
+	curves        [FLASH_CURVES_BYTES]byte
+	gimbal        [FLASH_GIMBAL_BYTES]byte // zeroes by default, profile disabled
+	angles        byte                     // zero by default, ZYX Euler angles
+	adaptive      [FLASH_ADAPTIVE_BYTES]byte
+	gestures      [FLASH_GESTURES_BYTES]byte
+	hold          [FLASH_HOLD_BYTES]byte
+	recenter      byte // 100ms units, 0 means instant
+	recenterModes [FLASH_RECENTER_MODES_BYTES]byte
+	autoCenter    [FLASH_AUTO_CENTER_BYTES]byte
+	prediction    [FLASH_PREDICTION_BYTES]byte
+	filters       [FLASH_FILTERS_BYTES]byte
+	pan           [FLASH_PAN_BYTES]byte
+	ppm           [FLASH_PPM_BYTES]byte
+	wired         byte // see "wiredPPM" and others
+	mavlink       [FLASH_MAVLINK_BYTES]byte
+	controller    [FLASH_CONTROLLER_BYTES]byte
 }
 
 func NewFlash() *Flash {
-	return &Flash{
+	fd := &Flash{
 		checksum:      FLASH_LENGTH,
 		length:        FLASH_LENGTH,
 		gyrCalOffsets: [FLASH_GYR_CAL_BLOCKS]int32{0, 0, 0},
 		deviceName:    [FLASH_DEVICE_NAME_BYTES]byte{'H', 'T'},
 		axisMapping:   [FLASH_AXIS_MAPPING_BYTES]byte{0x10, 0x11, 0x12}, // default mapping: all axes enabled, not inverted, mapped to first 3 channels
+		fusion:        0,                                                // default fusion: Madgwick
+		mounting:      [FLASH_MOUNTING_BYTES]byte{0, 0x00, 0x40},        // default mounting: flat, USB-C facing right, no adjustment
+		accCalOffsets: [FLASH_ACC_CAL_BLOCKS]int32{0, 0, 0},
+		accCalScales:  [FLASH_ACC_CAL_BLOCKS]int32{1_000_000, 1_000_000, 1_000_000}, // default scales: 1.0
+		adaptive:      [FLASH_ADAPTIVE_BYTES]byte{0x00, 5, 90, 10},                  // default adaptive gain: disabled, 5dps, 90dps, 10%
+		gestures:      [FLASH_GESTURES_BYTES]byte{0, 0, 0, 0, 3},                    // default gestures: no actions, spare channel 4
+		hold:          [FLASH_HOLD_BYTES]byte{0, 5},                                 // default hold mode: freeze, 0.5s blend
+		recenter:      5,                                                            // default re-center: 0.5s
+		autoCenter:    [FLASH_AUTO_CENTER_BYTES]byte{0x00, 30, 5, 15, 10},           // default auto center: disabled, 3dps, 5s, 15 degrees, 1dps
+		prediction:    [FLASH_PREDICTION_BYTES]byte{0, 10},                          // default prediction: disabled, 10 degrees cap
+		pan:           [FLASH_PAN_BYTES]byte{panModeAngle, 2, 36},                   // default pan: angle within ±180 degrees
+		ppm:           [FLASH_PPM_BYTES]byte{8, 45, 30, 0},                          // default PPM: 8 channels, 22.5ms frame, 300us spacer, not inverted
+		wired:         wiredPPM,                                                     // default wired output: PPM
+		mavlink:       [FLASH_MAVLINK_BYTES]byte{254, 25, 1, 1, 0, 6},               // default MAVLink: 254/25 to 1/1, gimbal manager, 57600 baud
+		controller:    [FLASH_CONTROLLER_BYTES]byte{12, 0, 30},                      // default gimbal controller: 115200 baud, not inverted, 300dps
+	}
+	for i := range fd.gyrTempModel {
+		fd.gyrTempModel[i] = [3]int16{flashGyrTempEmpty, flashGyrTempEmpty, flashGyrTempEmpty} // nothing learned yet
 	}
+	for i := 0; i < 3; i++ {
+		copy(fd.curves[i*CURVE_BYTES:], defaultCurve[:])    // default curves: linear, ±180 degrees full scale
+		copy(fd.filters[i*FILTER_BYTES:], defaultFilter[:]) // default filters: One-Euro, 1Hz min cutoff, beta 0.2
+	}
+	return fd
 }
 
 func (fd *Flash) IsEmpty() bool {
@@ -100,6 +172,233 @@ func (fd *Flash) Load() error {
 	println("  axis mapping:", fd.axisMapping[0], fd.axisMapping[1], fd.axisMapping[2])
 	offset += FLASH_AXIS_MAPPING_BYTES
 
+	// read fusion algorithm, best effort
+	if length < offset+FLASH_FUSION_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default fusion
+	}
+	fd.fusion = data[offset]
+	println("  fusion:", fd.fusion)
+	offset += FLASH_FUSION_BYTES
+
+	// read magnetometer calibration, best effort
+	if length < offset+FLASH_MAG_CAL_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just no magnetometer calibration
+	}
+	for i := range FLASH_MAG_CAL_BLOCKS {
+		fd.magCalOffsets[i] = toInt32(data[offset+i*4 : offset+(i+1)*4])
+		fd.magCalRadii[i] = toInt32(data[offset+(FLASH_MAG_CAL_BLOCKS+i)*4 : offset+(FLASH_MAG_CAL_BLOCKS+i+1)*4])
+	}
+	println("  magnetometer calibration:", fd.magCalOffsets[0], fd.magCalOffsets[1], fd.magCalOffsets[2], "/", fd.magCalRadii[0], fd.magCalRadii[1], fd.magCalRadii[2])
+	offset += FLASH_MAG_CAL_BYTES
+
+	// read mounting, best effort
+	if length < offset+FLASH_MOUNTING_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default mounting
+	}
+	for i := 0; i < FLASH_MOUNTING_BYTES; i++ {
+		fd.mounting[i] = data[offset+i]
+	}
+	println("  mounting:", fd.mounting[0])
+	offset += FLASH_MOUNTING_BYTES
+
+	// read accelerometer calibration, best effort
+	if length < offset+FLASH_ACC_CAL_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just no accelerometer calibration
+	}
+	for i := range FLASH_ACC_CAL_BLOCKS {
+		fd.accCalOffsets[i] = toInt32(data[offset+i*4 : offset+(i+1)*4])
+		fd.accCalScales[i] = toInt32(data[offset+(FLASH_ACC_CAL_BLOCKS+i)*4 : offset+(FLASH_ACC_CAL_BLOCKS+i+1)*4])
+	}
+	println("  accelerometer calibration:", fd.accCalOffsets[0], fd.accCalOffsets[1], fd.accCalOffsets[2], "/", fd.accCalScales[0], fd.accCalScales[1], fd.accCalScales[2])
+	offset += FLASH_ACC_CAL_BYTES
+
+	// read gyro temperature model, best effort
+	if length < offset+FLASH_GYR_TEMP_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just nothing learned yet
+	}
+	for i := range FLASH_GYR_TEMP_BINS {
+		for j := range 3 {
+			fd.gyrTempModel[i][j] = toInt16(data[offset+(i*3+j)*2 : offset+(i*3+j+1)*2])
+		}
+	}
+	println("  gyro temperature model:", fd.gyrTempBinsLearned(), "bins learned")
+	offset += FLASH_GYR_TEMP_BYTES
+
+	// read response curves, best effort
+	if length < offset+FLASH_CURVES_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default curves
+	}
+	for i := 0; i < FLASH_CURVES_BYTES; i++ {
+		fd.curves[i] = data[offset+i]
+	}
+	println("  curves: gains", fd.curves[2], fd.curves[CURVE_BYTES+2], fd.curves[2*CURVE_BYTES+2])
+	offset += FLASH_CURVES_BYTES
+
+	// read gimbal profile, best effort
+	if length < offset+FLASH_GIMBAL_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just no gimbal profile
+	}
+	for i := 0; i < FLASH_GIMBAL_BYTES; i++ {
+		fd.gimbal[i] = data[offset+i]
+	}
+	println("  gimbal: servo throws", fd.gimbal[0], fd.gimbal[GIMBAL_BYTES], fd.gimbal[2*GIMBAL_BYTES])
+	offset += FLASH_GIMBAL_BYTES
+
+	// read angles decomposition, best effort
+	if length < offset+FLASH_ANGLES_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default decomposition
+	}
+	fd.angles = data[offset]
+	println("  angles:", fd.angles)
+	offset += FLASH_ANGLES_BYTES
+
+	// read adaptive fusion gain, best effort
+	if length < offset+FLASH_ADAPTIVE_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default adaptive gain
+	}
+	for i := 0; i < FLASH_ADAPTIVE_BYTES; i++ {
+		fd.adaptive[i] = data[offset+i]
+	}
+	println("  adaptive gain:", fd.adaptive[0], fd.adaptive[1], fd.adaptive[2], fd.adaptive[3])
+	offset += FLASH_ADAPTIVE_BYTES
+
+	// read head gestures, best effort
+	if length < offset+FLASH_GESTURES_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default gestures
+	}
+	for i := 0; i < FLASH_GESTURES_BYTES; i++ {
+		fd.gestures[i] = data[offset+i]
+	}
+	println("  gestures:", fd.gestures[0], fd.gestures[1], fd.gestures[2], fd.gestures[3], fd.gestures[4])
+	offset += FLASH_GESTURES_BYTES
+
+	// read hold mode, best effort
+	if length < offset+FLASH_HOLD_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default hold mode
+	}
+	for i := 0; i < FLASH_HOLD_BYTES; i++ {
+		fd.hold[i] = data[offset+i]
+	}
+	println("  hold:", fd.hold[0], fd.hold[1])
+	offset += FLASH_HOLD_BYTES
+
+	// read re-center duration, best effort
+	if length < offset+FLASH_RECENTER_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default re-center duration
+	}
+	fd.recenter = data[offset]
+	println("  re-center:", fd.recenter)
+	offset += FLASH_RECENTER_BYTES
+
+	// read re-center modes, best effort
+	if length < offset+FLASH_RECENTER_MODES_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default re-center modes
+	}
+	for i := 0; i < FLASH_RECENTER_MODES_BYTES; i++ {
+		fd.recenterModes[i] = data[offset+i]
+	}
+	println("  re-center modes:", fd.recenterModes[0], fd.recenterModes[1], fd.recenterModes[2])
+	offset += FLASH_RECENTER_MODES_BYTES
+
+	// read auto center, best effort
+	if length < offset+FLASH_AUTO_CENTER_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default auto center
+	}
+	for i := 0; i < FLASH_AUTO_CENTER_BYTES; i++ {
+		fd.autoCenter[i] = data[offset+i]
+	}
+	println("  auto center:", fd.autoCenter[0], fd.autoCenter[1], fd.autoCenter[2], fd.autoCenter[3], fd.autoCenter[4])
+	offset += FLASH_AUTO_CENTER_BYTES
+
+	// read latency compensation, best effort
+	if length < offset+FLASH_PREDICTION_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default latency compensation
+	}
+	for i := 0; i < FLASH_PREDICTION_BYTES; i++ {
+		fd.prediction[i] = data[offset+i]
+	}
+	println("  prediction:", fd.prediction[0], fd.prediction[1])
+	offset += FLASH_PREDICTION_BYTES
+
+	// read output smoothing filters, best effort
+	if length < offset+FLASH_FILTERS_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default filters
+	}
+	for i := 0; i < FLASH_FILTERS_BYTES; i++ {
+		fd.filters[i] = data[offset+i]
+	}
+	println("  filters: types", fd.filters[0], fd.filters[FILTER_BYTES], fd.filters[2*FILTER_BYTES])
+	offset += FLASH_FILTERS_BYTES
+
+	// read continuous pan, best effort
+	if length < offset+FLASH_PAN_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default pan
+	}
+	for i := 0; i < FLASH_PAN_BYTES; i++ {
+		fd.pan[i] = data[offset+i]
+	}
+	println("  pan:", fd.pan[0], fd.pan[1], fd.pan[2])
+	offset += FLASH_PAN_BYTES
+
+	// read PPM output, best effort
+	if length < offset+FLASH_PPM_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default PPM output
+	}
+	for i := 0; i < FLASH_PPM_BYTES; i++ {
+		fd.ppm[i] = data[offset+i]
+	}
+	println("  PPM:", fd.ppm[0], fd.ppm[1], fd.ppm[2], fd.ppm[3])
+	offset += FLASH_PPM_BYTES
+
+	// read wired output protocol, best effort
+	if length < offset+FLASH_WIRED_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default wired output
+	}
+	fd.wired = data[offset]
+	println("  wired:", fd.wired)
+	offset += FLASH_WIRED_BYTES
+
+	// read MAVLink output, best effort
+	if length < offset+FLASH_MAVLINK_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default MAVLink output
+	}
+	for i := 0; i < FLASH_MAVLINK_BYTES; i++ {
+		fd.mavlink[i] = data[offset+i]
+	}
+	println("  MAVLink:", fd.mavlink[0], fd.mavlink[1], fd.mavlink[2], fd.mavlink[3], fd.mavlink[4], fd.mavlink[5])
+	offset += FLASH_MAVLINK_BYTES
+
+	// read gimbal controller output, best effort
+	if length < offset+FLASH_CONTROLLER_BYTES {
+		println("Incomplete flash data, length:", length)
+		return nil // this is fine, just default gimbal controller output
+	}
+	for i := 0; i < FLASH_CONTROLLER_BYTES; i++ {
+		fd.controller[i] = data[offset+i]
+	}
+	println("  controller:", fd.controller[0], fd.controller[1], fd.controller[2])
+	offset += FLASH_CONTROLLER_BYTES
+
 	return nil
 }
 
@@ -133,6 +432,149 @@ func (fd *Flash) Save() error {
 	println("  axis mapping:", fd.axisMapping[0], fd.axisMapping[1], fd.axisMapping[2])
 	offset += FLASH_AXIS_MAPPING_BYTES
 
+	// fusion algorithm
+	data[offset] = fd.fusion
+	println("  fusion:", fd.fusion)
+	offset += FLASH_FUSION_BYTES
+
+	// magnetometer calibration
+	for i := range FLASH_MAG_CAL_BLOCKS {
+		fromInt32(data[offset+i*4:offset+(i+1)*4], fd.magCalOffsets[i])
+		fromInt32(data[offset+(FLASH_MAG_CAL_BLOCKS+i)*4:offset+(FLASH_MAG_CAL_BLOCKS+i+1)*4], fd.magCalRadii[i])
+	}
+	println("  magnetometer calibration:", fd.magCalOffsets[0], fd.magCalOffsets[1], fd.magCalOffsets[2], "/", fd.magCalRadii[0], fd.magCalRadii[1], fd.magCalRadii[2])
+	offset += FLASH_MAG_CAL_BYTES
+
+	// mounting
+	for i := 0; i < FLASH_MOUNTING_BYTES; i++ {
+		data[offset+i] = fd.mounting[i]
+	}
+	println("  mounting:", fd.mounting[0])
+	offset += FLASH_MOUNTING_BYTES
+
+	// accelerometer calibration
+	for i := range FLASH_ACC_CAL_BLOCKS {
+		fromInt32(data[offset+i*4:offset+(i+1)*4], fd.accCalOffsets[i])
+		fromInt32(data[offset+(FLASH_ACC_CAL_BLOCKS+i)*4:offset+(FLASH_ACC_CAL_BLOCKS+i+1)*4], fd.accCalScales[i])
+	}
+	println("  accelerometer calibration:", fd.accCalOffsets[0], fd.accCalOffsets[1], fd.accCalOffsets[2], "/", fd.accCalScales[0], fd.accCalScales[1], fd.accCalScales[2])
+	offset += FLASH_ACC_CAL_BYTES
+
+	// gyro temperature model
+	for i := range FLASH_GYR_TEMP_BINS {
+		for j := range 3 {
+			fromInt16(data[offset+(i*3+j)*2:offset+(i*3+j+1)*2], fd.gyrTempModel[i][j])
+		}
+	}
+	println("  gyro temperature model:", fd.gyrTempBinsLearned(), "bins learned")
+	offset += FLASH_GYR_TEMP_BYTES
+
+	// response curves
+	for i := 0; i < FLASH_CURVES_BYTES; i++ {
+		data[offset+i] = fd.curves[i]
+	}
+	println("  curves: gains", fd.curves[2], fd.curves[CURVE_BYTES+2], fd.curves[2*CURVE_BYTES+2])
+	offset += FLASH_CURVES_BYTES
+
+	// gimbal profile
+	for i := 0; i < FLASH_GIMBAL_BYTES; i++ {
+		data[offset+i] = fd.gimbal[i]
+	}
+	println("  gimbal: servo throws", fd.gimbal[0], fd.gimbal[GIMBAL_BYTES], fd.gimbal[2*GIMBAL_BYTES])
+	offset += FLASH_GIMBAL_BYTES
+
+	// angles decomposition
+	data[offset] = fd.angles
+	println("  angles:", fd.angles)
+	offset += FLASH_ANGLES_BYTES
+
+	// adaptive fusion gain
+	for i := 0; i < FLASH_ADAPTIVE_BYTES; i++ {
+		data[offset+i] = fd.adaptive[i]
+	}
+	println("  adaptive gain:", fd.adaptive[0], fd.adaptive[1], fd.adaptive[2], fd.adaptive[3])
+	offset += FLASH_ADAPTIVE_BYTES
+
+	// head gestures
+	for i := 0; i < FLASH_GESTURES_BYTES; i++ {
+		data[offset+i] = fd.gestures[i]
+	}
+	println("  gestures:", fd.gestures[0], fd.gestures[1], fd.gestures[2], fd.gestures[3], fd.gestures[4])
+	offset += FLASH_GESTURES_BYTES
+
+	// hold mode
+	for i := 0; i < FLASH_HOLD_BYTES; i++ {
+		data[offset+i] = fd.hold[i]
+	}
+	println("  hold:", fd.hold[0], fd.hold[1])
+	offset += FLASH_HOLD_BYTES
+
+	// re-center duration
+	data[offset] = fd.recenter
+	println("  re-center:", fd.recenter)
+	offset += FLASH_RECENTER_BYTES
+
+	// re-center modes
+	for i := 0; i < FLASH_RECENTER_MODES_BYTES; i++ {
+		data[offset+i] = fd.recenterModes[i]
+	}
+	println("  re-center modes:", fd.recenterModes[0], fd.recenterModes[1], fd.recenterModes[2])
+	offset += FLASH_RECENTER_MODES_BYTES
+
+	// auto center
+	for i := 0; i < FLASH_AUTO_CENTER_BYTES; i++ {
+		data[offset+i] = fd.autoCenter[i]
+	}
+	println("  auto center:", fd.autoCenter[0], fd.autoCenter[1], fd.autoCenter[2], fd.autoCenter[3], fd.autoCenter[4])
+	offset += FLASH_AUTO_CENTER_BYTES
+
+	// latency compensation
+	for i := 0; i < FLASH_PREDICTION_BYTES; i++ {
+		data[offset+i] = fd.prediction[i]
+	}
+	println("  prediction:", fd.prediction[0], fd.prediction[1])
+	offset += FLASH_PREDICTION_BYTES
+
+	// output smoothing filters
+	for i := 0; i < FLASH_FILTERS_BYTES; i++ {
+		data[offset+i] = fd.filters[i]
+	}
+	println("  filters: types", fd.filters[0], fd.filters[FILTER_BYTES], fd.filters[2*FILTER_BYTES])
+	offset += FLASH_FILTERS_BYTES
+
+	// continuous pan
+	for i := 0; i < FLASH_PAN_BYTES; i++ {
+		data[offset+i] = fd.pan[i]
+	}
+	println("  pan:", fd.pan[0], fd.pan[1], fd.pan[2])
+	offset += FLASH_PAN_BYTES
+
+	// PPM output
+	for i := 0; i < FLASH_PPM_BYTES; i++ {
+		data[offset+i] = fd.ppm[i]
+	}
+	println("  PPM:", fd.ppm[0], fd.ppm[1], fd.ppm[2], fd.ppm[3])
+	offset += FLASH_PPM_BYTES
+
+	// wired output protocol
+	data[offset] = fd.wired
+	println("  wired:", fd.wired)
+	offset += FLASH_WIRED_BYTES
+
+	// MAVLink output
+	for i := 0; i < FLASH_MAVLINK_BYTES; i++ {
+		data[offset+i] = fd.mavlink[i]
+	}
+	println("  MAVLink:", fd.mavlink[0], fd.mavlink[1], fd.mavlink[2], fd.mavlink[3], fd.mavlink[4], fd.mavlink[5])
+	offset += FLASH_MAVLINK_BYTES
+
+	// gimbal controller output
+	for i := 0; i < FLASH_CONTROLLER_BYTES; i++ {
+		data[offset+i] = fd.controller[i]
+	}
+	println("  controller:", fd.controller[0], fd.controller[1], fd.controller[2])
+	offset += FLASH_CONTROLLER_BYTES
+
 	// xor all bytes, but the first
 	checksum := byte(0)
 	for _, b := range data[1:] {
@@ -170,6 +612,68 @@ func (fd *Flash) GyrCalOffsets() [FLASH_GYR_CAL_BLOCKS]int32 {
 	return fd.gyrCalOffsets
 }
 
+func (fd *Flash) SetMagCal(offsets, radii [FLASH_MAG_CAL_BLOCKS]int32, threshold int32) bool {
+	overThreshold := false
+	for i := range offsets {
+		if abs(fd.magCalOffsets[i]-offsets[i]) > threshold || abs(fd.magCalRadii[i]-radii[i]) > threshold {
+			overThreshold = true
+			break
+		}
+	}
+	if overThreshold {
+		fd.magCalOffsets = offsets
+		fd.magCalRadii = radii
+	}
+	return overThreshold
+}
+
+func (fd *Flash) MagCal() (offsets, radii [FLASH_MAG_CAL_BLOCKS]int32) {
+	return fd.magCalOffsets, fd.magCalRadii
+}
+
+func (fd *Flash) SetAccCal(offsets, scales [FLASH_ACC_CAL_BLOCKS]int32) bool {
+	if fd.accCalOffsets == offsets && fd.accCalScales == scales {
+		return false
+	}
+	fd.accCalOffsets = offsets
+	fd.accCalScales = scales
+	return true
+}
+
+func (fd *Flash) AccCal() (offsets, scales [FLASH_ACC_CAL_BLOCKS]int32) {
+	return fd.accCalOffsets, fd.accCalScales
+}
+
+func (fd *Flash) SetGyrTempModel(model [FLASH_GYR_TEMP_BINS][3]int16, threshold int32) bool {
+	overThreshold := false
+	for i := range model {
+		for j := range model[i] {
+			learned := fd.gyrTempModel[i][j] == flashGyrTempEmpty && model[i][j] != flashGyrTempEmpty
+			if learned || abs(int32(fd.gyrTempModel[i][j])-int32(model[i][j])) > threshold {
+				overThreshold = true
+			}
+		}
+	}
+	if overThreshold {
+		fd.gyrTempModel = model
+	}
+	return overThreshold
+}
+
+func (fd *Flash) GyrTempModel() [FLASH_GYR_TEMP_BINS][3]int16 {
+	return fd.gyrTempModel
+}
+
+func (fd *Flash) gyrTempBinsLearned() int {
+	n := 0
+	for _, bin := range fd.gyrTempModel {
+		if bin[0] != flashGyrTempEmpty {
+			n++
+		}
+	}
+	return n
+}
+
 func (fd *Flash) SetDeviceName(name string) bool {
 	newName := false
 	n := 0
@@ -210,10 +714,235 @@ func (fd *Flash) AxisMapping() [FLASH_AXIS_MAPPING_BYTES]byte {
 	return fd.axisMapping
 }
 
+func (fd *Flash) SetFusion(fusion byte) bool {
+	if fd.fusion == fusion {
+		return false
+	}
+	fd.fusion = fusion
+	return true
+}
+
+func (fd *Flash) Fusion() byte {
+	return fd.fusion
+}
+
+func (fd *Flash) SetMounting(mounting [FLASH_MOUNTING_BYTES]byte) bool {
+	if fd.mounting == mounting {
+		return false
+	}
+	fd.mounting = mounting
+	return true
+}
+
+func (fd *Flash) Mounting() [FLASH_MOUNTING_BYTES]byte {
+	return fd.mounting
+}
+
+func (fd *Flash) SetCurves(curves [FLASH_CURVES_BYTES]byte) bool {
+	if fd.curves == curves {
+		return false
+	}
+	fd.curves = curves
+	return true
+}
+
+func (fd *Flash) Curves() [FLASH_CURVES_BYTES]byte {
+	return fd.curves
+}
+
+func (fd *Flash) SetGimbal(gimbal [FLASH_GIMBAL_BYTES]byte) bool {
+	if fd.gimbal == gimbal {
+		return false
+	}
+	fd.gimbal = gimbal
+	return true
+}
+
+func (fd *Flash) Gimbal() [FLASH_GIMBAL_BYTES]byte {
+	return fd.gimbal
+}
+
+func (fd *Flash) SetAngles(angles byte) bool {
+	if fd.angles == angles {
+		return false
+	}
+	fd.angles = angles
+	return true
+}
+
+func (fd *Flash) Angles() byte {
+	return fd.angles
+}
+
+func (fd *Flash) SetAdaptive(adaptive [FLASH_ADAPTIVE_BYTES]byte) bool {
+	if fd.adaptive == adaptive {
+		return false
+	}
+	fd.adaptive = adaptive
+	return true
+}
+
+func (fd *Flash) Adaptive() [FLASH_ADAPTIVE_BYTES]byte {
+	return fd.adaptive
+}
+
+func (fd *Flash) SetGestures(gestures [FLASH_GESTURES_BYTES]byte) bool {
+	if fd.gestures == gestures {
+		return false
+	}
+	fd.gestures = gestures
+	return true
+}
+
+func (fd *Flash) Gestures() [FLASH_GESTURES_BYTES]byte {
+	return fd.gestures
+}
+
+func (fd *Flash) SetHold(hold [FLASH_HOLD_BYTES]byte) bool {
+	if fd.hold == hold {
+		return false
+	}
+	fd.hold = hold
+	return true
+}
+
+func (fd *Flash) Hold() [FLASH_HOLD_BYTES]byte {
+	return fd.hold
+}
+
+func (fd *Flash) SetRecenter(recenter byte) bool {
+	if fd.recenter == recenter {
+		return false
+	}
+	fd.recenter = recenter
+	return true
+}
+
+func (fd *Flash) Recenter() byte {
+	return fd.recenter
+}
+
+func (fd *Flash) SetRecenterModes(recenterModes [FLASH_RECENTER_MODES_BYTES]byte) bool {
+	if fd.recenterModes == recenterModes {
+		return false
+	}
+	fd.recenterModes = recenterModes
+	return true
+}
+
+func (fd *Flash) RecenterModes() [FLASH_RECENTER_MODES_BYTES]byte {
+	return fd.recenterModes
+}
+
+func (fd *Flash) SetAutoCenter(autoCenter [FLASH_AUTO_CENTER_BYTES]byte) bool {
+	if fd.autoCenter == autoCenter {
+		return false
+	}
+	fd.autoCenter = autoCenter
+	return true
+}
+
+func (fd *Flash) AutoCenter() [FLASH_AUTO_CENTER_BYTES]byte {
+	return fd.autoCenter
+}
+
+func (fd *Flash) SetPrediction(prediction [FLASH_PREDICTION_BYTES]byte) bool {
+	if fd.prediction == prediction {
+		return false
+	}
+	fd.prediction = prediction
+	return true
+}
+
+func (fd *Flash) Prediction() [FLASH_PREDICTION_BYTES]byte {
+	return fd.prediction
+}
+
+func (fd *Flash) SetFilters(filters [FLASH_FILTERS_BYTES]byte) bool {
+	if fd.filters == filters {
+		return false
+	}
+	fd.filters = filters
+	return true
+}
+
+func (fd *Flash) Filters() [FLASH_FILTERS_BYTES]byte {
+	return fd.filters
+}
+
+func (fd *Flash) SetPan(pan [FLASH_PAN_BYTES]byte) bool {
+	if fd.pan == pan {
+		return false
+	}
+	fd.pan = pan
+	return true
+}
+
+func (fd *Flash) Pan() [FLASH_PAN_BYTES]byte {
+	return fd.pan
+}
+
+func (fd *Flash) SetPPM(ppm [FLASH_PPM_BYTES]byte) bool {
+	if fd.ppm == ppm {
+		return false
+	}
+	fd.ppm = ppm
+	return true
+}
+
+func (fd *Flash) PPM() [FLASH_PPM_BYTES]byte {
+	return fd.ppm
+}
+
+func (fd *Flash) SetWired(wired byte) bool {
+	if fd.wired == wired {
+		return false
+	}
+	fd.wired = wired
+	return true
+}
+
+func (fd *Flash) Wired() byte {
+	return fd.wired
+}
+
+func (fd *Flash) SetMavlink(mavlink [FLASH_MAVLINK_BYTES]byte) bool {
+	if fd.mavlink == mavlink {
+		return false
+	}
+	fd.mavlink = mavlink
+	return true
+}
+
+func (fd *Flash) Mavlink() [FLASH_MAVLINK_BYTES]byte {
+	return fd.mavlink
+}
+
+func (fd *Flash) SetController(controller [FLASH_CONTROLLER_BYTES]byte) bool {
+	if fd.controller == controller {
+		return false
+	}
+	fd.controller = controller
+	return true
+}
+
+func (fd *Flash) Controller() [FLASH_CONTROLLER_BYTES]byte {
+	return fd.controller
+}
+
 func toInt32(b []byte) int32 {
 	return int32(b[0]) | int32(b[1])<<8 | int32(b[2])<<16 | int32(b[3])<<24
 }
 
+func toInt16(b []byte) int16 {
+	return int16(b[0]) | int16(b[1])<<8
+}
+
+func fromInt16(b []byte, v int16) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+}
+
 func fromInt32(b []byte, v int32) {
 	b[0] = byte(v)
 	b[1] = byte(v >> 8)
@@ -0,0 +1,60 @@
+package main
+
+import "github.com/ysoldak/HeadTracker/src/orientation"
+
+// Gestures setting has format: action for nod, shake, tilt hold left and tilt hold right, then spare channel index
+const (
+	gestureActionNone    = 0
+	gestureActionCenter  = 1 // re-center orientation
+	gestureActionFreeze  = 2 // toggle output hold, see "Hold"
+	gestureActionProfile = 3 // toggle gimbal profile on and off
+	gestureActionSpare   = 4 // flip spare channel between low and high
+)
+
+const (
+	spareLow  = 1000
+	spareHigh = 2000
+)
+
+var gestureNames = [...]string{
+	orientation.GestureNod:       "nod",
+	orientation.GestureShake:     "shake",
+	orientation.GestureTiltLeft:  "tilt left",
+	orientation.GestureTiltRight: "tilt right",
+}
+
+// Act on recognised head gesture according to configuration
+func handleGesture(gesture orientation.Gesture) {
+	if gesture == orientation.GestureNone {
+		return
+	}
+	action := state.gestures[gesture-orientation.GestureNod]
+	println("Gesture:", gestureNames[gesture], "action:", action)
+	switch action {
+	case gestureActionCenter:
+		resetOrientation(state.recenterModes[recenterSourceTaps])
+	case gestureActionFreeze:
+		toggleHold()
+	case gestureActionProfile:
+		state.profileOff = !state.profileOff
+		setCurves(state.curves, state.gimbal)
+	case gestureActionSpare:
+		state.spare = !state.spare
+	}
+}
+
+// Spare channel is output only when a gesture flips it, otherwise channel is left alone
+func setSpareChannel() {
+	used := false
+	for _, action := range state.gestures[:4] {
+		used = used || action == gestureActionSpare
+	}
+	if !used {
+		return
+	}
+	value := uint16(spareLow)
+	if state.spare {
+		value = spareHigh
+	}
+	t.SetChannel(int(state.gestures[4]), value)
+}
@@ -0,0 +1,38 @@
+package main
+
+// Gimbal kinematics profile, camera follows head by exactly the same angle.
+//
+// Every axis has 4 bytes:
+// - throw      servo throw in degrees for standard range (988..2012us), 0 disables the profile for the axis,
+// - gear       gear ratio, camera turns (first byte) per servo turns (second byte), e.g. 2:1 pan and 3:2 tilt,
+// - flags      bit 0 for extended range (732..2268us, 1.5 times standard range, servo throw is 1.5 times larger too).
+//
+// When enabled, the profile overrides gain of the response curve ("Curve"), so that full scale output
+// turns the camera by the same angle as the head; output is clamped to the range servo can do.
+// Other curve settings still apply, keep them default for exact tracking.
+//
+// Example: "5A 02 01 01" is 90 degrees servo, 2:1 gear, extended range (camera turns ±135 degrees)
+
+const GIMBAL_BYTES = 4
+
+const gimbalExtendedScale = 768 // us, full scale output in extended range (732..2268us)
+
+// Apply gimbal profile to a curve, nop when profile is disabled
+func (c *Curve) SetGimbal(b [GIMBAL_BYTES]byte) {
+	throw, camera, servo, extended := float64(b[0]), float64(b[1]), float64(b[2]), b[3]&0x01 == 0x01
+	if throw == 0 {
+		return
+	}
+	if camera == 0 || servo == 0 {
+		camera, servo = 1, 1
+	}
+	c.scale = curveScale
+	c.gain = throw / 2 * camera / servo // head (and camera) degrees for full scale in standard range
+	if extended {
+		c.scale = gimbalExtendedScale
+		c.gain *= float64(gimbalExtendedScale) / curveScale
+	}
+	if c.deadband >= c.gain {
+		c.deadband = 0
+	}
+}
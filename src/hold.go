@@ -0,0 +1,131 @@
+package main
+
+// Output hold, channels stop following the head, e.g. while looking at radio screen.
+//
+// Setting has 2 bytes:
+// - mode   0 channels freeze at their current values, 1 channels go to center,
+// - blend  100ms units, time channels take to reach hold values and to return to live tracking, 0 means instant.
+//
+// Hold is toggled with a long button press, a triple tap, "H" command or a head gesture (see "gestureActionFreeze").
+// Toggling during a blend starts a new blend from where the output is, so channels never jump.
+// Trainers that take orientation or angles as is (see "Attitude" and "Angles") hold and blend the same way.
+
+import (
+	"math"
+
+	mgl "github.com/go-gl/mathgl/mgl64"
+)
+
+const HOLD_BYTES = 2
+
+const (
+	holdModeFreeze = 0
+	holdModeCenter = 1
+)
+
+type Hold struct {
+	active bool
+	center bool
+	blend  uint16 // ms
+	left   uint16 // ms, of blend
+
+	held [3]uint16  // channel values to hold
+	from [3]float64 // channel values blend starts from
+	out  [3]float64 // channel values output last
+
+	attitude     mgl.Quat   // orientation output last, see "Attitude"
+	attitudeHeld mgl.Quat   // orientation to hold
+	attitudeFrom mgl.Quat   // orientation blend starts from
+	angles       [3]float64 // angles output last, see "Angles"
+	anglesHeld   [3]float64 // angles to hold
+	anglesFrom   [3]float64 // angles blend starts from
+}
+
+// Toggle output hold and tell about it
+func toggleHold() {
+	if state.holdOutput.Toggle(state.panOutput.Rate()) {
+		println("Output hold on")
+	} else {
+		println("Output hold off")
+	}
+}
+
+func (h *Hold) Configure(b [HOLD_BYTES]byte) {
+	h.center = b[0] == holdModeCenter
+	h.blend = uint16(b[1]) * 100
+	h.left = min(h.left, h.blend) // blend in progress never goes backwards
+}
+
+// Toggle hold, returns true when hold is on
+// Pan rate output (see "Pan") holds at center, frozen rate would keep continuous servo turning.
+func (h *Hold) Toggle(panRate bool) bool {
+	h.active = !h.active
+	if h.active {
+		for i, v := range h.out {
+			h.held[i] = uint16(v)
+			if h.center || (i == 2 && panRate) {
+				h.held[i] = curveCenter
+			}
+		}
+		h.attitudeHeld, h.anglesHeld = h.attitude, h.angles
+		if h.center {
+			h.attitudeHeld, h.anglesHeld = mgl.QuatIdent(), [3]float64{}
+		}
+	}
+	h.from = h.out
+	h.attitudeFrom, h.anglesFrom = h.attitude, h.angles
+	h.left = h.blend
+	return h.active
+}
+
+// Channel value for an axis, given its live value
+func (h *Hold) Channel(axis int, live uint16) uint16 {
+	target := float64(live)
+	if h.active {
+		target = float64(h.held[axis])
+	}
+	h.out[axis] = target
+	if h.left > 0 && h.blend > 0 {
+		p := 1 - float64(h.left)/float64(h.blend)
+		h.out[axis] = h.from[axis] + (target-h.from[axis])*p
+	}
+	return uint16(h.out[axis])
+}
+
+// Orientation (w, x, y, z) for trainers that take it as is, given live orientation, slerps during blend
+func (h *Hold) Attitude(live [4]float64) [4]float64 {
+	h.attitude = mgl.Quat{W: live[0], V: mgl.Vec3{live[1], live[2], live[3]}}
+	if h.active {
+		h.attitude = h.attitudeHeld
+	}
+	if h.left > 0 && h.blend > 0 {
+		p := 1 - float64(h.left)/float64(h.blend)
+		h.attitude = mgl.QuatSlerp(h.attitudeFrom, h.attitude, p)
+	}
+	return [4]float64{h.attitude.W, h.attitude.V[0], h.attitude.V[1], h.attitude.V[2]}
+}
+
+// Angles (radians) for trainers that take them as is, given live angles, pan takes the short way round during blend
+func (h *Hold) Angles(live [3]float64) [3]float64 {
+	h.angles = live
+	if h.active {
+		h.angles = h.anglesHeld
+	}
+	if h.left > 0 && h.blend > 0 {
+		p := 1 - float64(h.left)/float64(h.blend)
+		for i, target := range h.angles {
+			d := target - h.anglesFrom[i]
+			if i == 2 { // pan
+				d = math.Remainder(d, 2*math.Pi)
+			}
+			h.angles[i] = h.anglesFrom[i] + d*p
+		}
+		h.angles[2] = math.Remainder(h.angles[2], 2*math.Pi)
+	}
+	return h.angles
+}
+
+// Advance blend by elapsed time (ms), once per main loop iteration
+func (h *Hold) Update(elapsed uint16) {
+	h.left -= min(h.left, elapsed)
+}
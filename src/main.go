@@ -1,7 +1,6 @@
 package main
 
 import (
-	"math"
 	"runtime"
 	"strconv"
 	"time"
@@ -21,13 +20,13 @@ const (
 	BLINK_WARM_COUNT = 100    // warm up / calibration indicator
 	BLINK_PARA_COUNT = 200    // para (bluetooth) state indicator
 	TRACE_COUNT      = 1_000  // tracing to serial output, every 1 second
-)
-
-const (
-	radToMs = 512.0 / math.Pi
+	BUTTON_LONG      = 1_000  // button press is long after 1 second
+	FAILSAFE_COUNT   = 25     // failsafe after IMU failed to read for 25 periods (500ms)
 )
 
 const flashStoreThreshold = 100_000
+const flashMagStoreThreshold = 2_000                         // nT
+const flashGyrTempStoreThreshold = flashStoreThreshold / 100 // model stores offsets in 100s of udps
 
 var (
 	d *display.Display
@@ -43,16 +42,69 @@ type Trainer interface {
 	SetChannel(num int, v uint16)
 }
 
+// Trainers that tell receiving end about tracker health, e.g. SBUS flags
+type HealthTrainer interface {
+	SetHealth(lost, failsafe bool)
+}
+
+// Trainers that take orientation as is, e.g. MAVLink gimbal attitude
+type AttitudeTrainer interface {
+	SetAttitude(q [4]float64)
+}
+
+// Trainers that take orientation angles, e.g. gimbal controllers
+type AnglesTrainer interface {
+	SetAngles(angles [3]float64)
+}
+
+// Wired output setting selects protocol when wired output is selected by pin, see "pinSelectPPM"
+const (
+	wiredPPM       = 0
+	wiredSBUS      = 1
+	wiredCRSF      = 2
+	wiredMAVLink   = 3
+	wiredSTorM32   = 4
+	wiredSimpleBGC = 5
+)
+
 var (
 	tickPeriod *time.Ticker
 )
 
 var state struct {
-	address     string
-	channels    [3]uint16
-	connected   bool
-	deviceName  string
-	axisMapping [3]byte
+	address       string
+	channels      [3]uint16
+	connected     bool
+	deviceName    string
+	axisMapping   [3]byte
+	fusion        byte
+	mounting      [9]byte
+	curves        [3 * CURVE_BYTES]byte
+	gimbal        [3 * GIMBAL_BYTES]byte
+	angles        byte
+	adaptive      [4]byte
+	axisCurves    [3]Curve
+	axisFilters   [3]Filter
+	panOutput     Pan
+	gestures      [5]byte
+	hold          [2]byte
+	recenter      byte
+	recenterModes [3]byte
+	autoCenter    [5]byte
+	prediction    [2]byte
+	filters       [3 * FILTER_BYTES]byte
+	pan           [3]byte
+	ppm           [4]byte
+	wired         byte
+	mavlink       [6]byte
+	controller    [3]byte
+
+	holdOutput Hold
+	profileOff bool // gimbal profile turned off, see "gestureActionProfile"
+	spare      bool // spare channel high, see "gestureActionSpare"
+
+	accCalibrating bool
+	recording      bool // motion to serial output, see "printMotion"
 }
 
 func init() {
@@ -81,6 +133,8 @@ func init() {
 
 	f = NewFlash()
 
+	h = &BluetoothCallbackHandler{}
+
 	tickPeriod = time.NewTicker(PERIOD * time.Millisecond)
 
 }
@@ -153,7 +207,7 @@ func main() {
 		}
 
 		if time.Now().After(stopTime) && !f.IsEmpty() { // when had some calibration already, force it if was not able to find better quickly
-			o.SetOffsets(f.GyrCalOffsets())
+			o.SetOffsets(storedOffsets())
 			o.SetStable(true)
 		}
 		if o.Stable() {
@@ -168,11 +222,45 @@ func main() {
 	saveState(0)
 
 	// Trainer (Bluetooth or PPM)
-	if !pinSelectPPM.Get() { // Low means connected to GND => PPM output requested
-		t = trainer.NewPPM(pinOutputPPM) // PPM wire
+	if !pinSelectPPM.Get() { // Low means connected to GND => wired output requested
+		switch state.wired {
+		case wiredSBUS:
+			t = trainer.NewSBUS(pinOutputSBUS) // SBUS wire, on UART TX pin too
+		case wiredCRSF:
+			t = trainer.NewCRSF(uartOutput) // CRSF wire
+		case wiredMAVLink:
+			t = trainer.NewMAVLink(uartOutput, state.mavlink) // MAVLink wire
+		case wiredSTorM32:
+			t = trainer.NewSTorM32(uartOutput, state.controller) // STorM32 wire
+		case wiredSimpleBGC:
+			t = trainer.NewSimpleBGC(uartOutput, state.controller) // SimpleBGC wire
+		default:
+			t = trainer.NewPPM(pinOutputPPM, state.ppm) // PPM wire
+		}
 		state.connected = true
 	} else {
-		t = trainer.NewPara(state.deviceName, state.axisMapping, &BluetoothCallbackHandler{})
+		t = trainer.NewPara(trainer.ParaSettings{
+			DeviceName:    state.deviceName,
+			AxisMapping:   state.axisMapping,
+			Fusion:        state.fusion,
+			Mounting:      state.mounting,
+			Curves:        state.curves,
+			Gimbal:        state.gimbal,
+			Angles:        state.angles,
+			Adaptive:      state.adaptive,
+			Gestures:      state.gestures,
+			Hold:          state.hold,
+			Recenter:      state.recenter,
+			RecenterModes: state.recenterModes,
+			AutoCenter:    state.autoCenter,
+			Prediction:    state.prediction,
+			Filters:       state.filters,
+			Pan:           state.pan,
+			PPM:           state.ppm,
+			Wired:         state.wired,
+			Mavlink:       state.mavlink,
+			Controller:    state.controller,
+		}, h)
 		state.connected = false
 	}
 	state.address = t.Start()
@@ -185,17 +273,35 @@ func main() {
 	}
 	d.Update()
 
+	// drop samples piled up in IMU FIFO during calibration and start up, so first update does not integrate them
+	i.Flush()
+
 	// main loop
 	iter = 0
 	for range tickPeriod.C {
 
 		pinDebugMain.Set(!pinDebugMain.Get())
 
-		// check for reset request
-		if !pinResetCenter.Get() || (iter%400 == 0 && i.ReadTap()) { // Button pressed OR [double] tap registered (shall not read register more frequently than double tap duration)
-			o.Reset()
-			println("Orientation reset via pin or double tap")
+		// check for reset and hold requests, taps are detected on previous update
+		tap := o.Tap()
+		short, long := readButton()
+		if short { // Button pressed
+			resetOrientation(state.recenterModes[recenterSourceButton])
+			println("Orientation reset via pin")
 		}
+		if tap == orientation.TapDouble || (iter%400 == 0 && i.ReadTap()) { // Double tap, detected or registered by chip (shall not read register more frequently than double tap duration)
+			resetOrientation(state.recenterModes[recenterSourceTaps])
+			println("Orientation reset via double tap")
+		}
+		if long || tap == orientation.TapTriple { // Button held OR triple tap
+			toggleHold()
+		}
+
+		// act on head gesture, recognised on previous update
+		handleGesture(o.Gesture())
+
+		// check for commands from serial console
+		handleSerial()
 
 		// update orientation, every 20ms (~2360us)
 		pinDebugData.High()
@@ -203,10 +309,16 @@ func main() {
 		pinDebugData.Low()
 
 		// set channels, every 20ms (~300us)
+		var angles [3]float64 // filtered
 		for i, a := range o.Angles() {
-			state.channels[i] = angleToChannel(a)
+			a = state.axisFilters[i].Apply(a)
+			angles[i] = a
+			if i == 2 { // pan
+				a = state.panOutput.Angle(a)
+			}
+			state.channels[i] = state.holdOutput.Channel(i, state.axisCurves[i].Channel(a))
 			d.SetBar(byte(i), int16(1500-state.channels[i])/10, false)
-			chIndex := state.axisMapping[i] & 0x07 // channel index
+			chIndex := state.axisMapping[i] & 0x0F // channel index
 			chValue := state.channels[i]
 			if state.axisMapping[i]&0x10 != 0x10 { // axis disabled
 				chValue = 1500
@@ -216,15 +328,28 @@ func main() {
 			}
 			t.SetChannel(int(chIndex), chValue)
 		}
+		setSpareChannel()
+		if at, ok := t.(AttitudeTrainer); ok {
+			at.SetAttitude(state.holdOutput.Attitude(o.Quaternion()))
+		}
+		if at, ok := t.(AnglesTrainer); ok {
+			at.SetAngles(state.holdOutput.Angles(angles))
+		}
+		state.holdOutput.Update(PERIOD) // after all outputs, so they blend in step
+		if ht, ok := t.(HealthTrainer); ok {
+			ht.SetHealth(o.Failures() > 0, o.Failures() >= FAILSAFE_COUNT)
+		}
 
 		// update display, every 100ms (~15000us)
 		updateDisplay(iter + PERIOD) // slow (when display is connected, shall not clash with anything else, so offset by one period)
 
 		// handle state, period and performance varies
-		blinkMain(iter)  // very fast
-		blinkPara(iter)  // very fast
-		saveState(iter)  // very slow (~85300us, can affect sensor fusion if executed too often; as it is so slow no point to offset it)
-		printState(iter) // fast (~1500us)
+		blinkMain(iter)           // very fast
+		blinkPara(iter)           // very fast
+		checkAccCalibration(iter) // very fast, slow when calibration is done
+		saveState(iter)           // very slow (~85300us, can affect sensor fusion if executed too often; as it is so slow no point to offset it)
+		printState(iter)          // fast (~1500us)
+		printMotion()             // fast, when recording
 
 		iter += PERIOD
 		iter %= 60_000
@@ -236,15 +361,127 @@ func main() {
 
 // --- Core ----
 
-func angleToChannel(angle float64) uint16 {
-	result := uint16(1500 + angle*radToMs)
-	if result < 988 {
-		return 988
+// Fusion setting has format 0b000MAAAA, where
+// - 'M'    bit for magnetometer (9D fusion) enabled(1)/disabled(0),
+// - 'AAAA' four bits for fusion algorithm.
+func setFusion(fusion byte) {
+	o.SetFusion(orientation.FusionKind(fusion & 0x0F))
+	o.SetMagnetometer(fusion&0x10 == 0x10)
+}
+
+// Mounting setting has format: orientation index (0-23) followed by adjustment quaternion (w, x, y, z),
+// each component is int16, little endian, 16384 means 1.0.
+func setMounting(mounting [9]byte) {
+	var adjustment [4]float64
+	for i := range adjustment {
+		adjustment[i] = float64(int16(mounting[1+i*2])|int16(mounting[2+i*2])<<8) / 16384
+	}
+	o.SetMounting(mounting[0], adjustment)
+}
+
+// Curves setting has format: 6 bytes per axis, see "Curve"
+// Gimbal setting has format: 4 bytes per axis, see "Gimbal", ignored while turned off by a gesture
+func setCurves(curves [3 * CURVE_BYTES]byte, gimbal [3 * GIMBAL_BYTES]byte) {
+	for i := range state.axisCurves {
+		var b [CURVE_BYTES]byte
+		copy(b[:], curves[i*CURVE_BYTES:])
+		state.axisCurves[i] = NewCurve(b)
+		var g [GIMBAL_BYTES]byte
+		copy(g[:], gimbal[i*GIMBAL_BYTES:])
+		if !state.profileOff {
+			state.axisCurves[i].SetGimbal(g)
+		}
 	}
-	if result > 2012 {
-		return 2012
+}
+
+// Filters setting has format: 3 bytes per axis, see "Filter"
+func setFilters(filters [3 * FILTER_BYTES]byte) {
+	for i := range state.axisFilters {
+		var b [FILTER_BYTES]byte
+		copy(b[:], filters[i*FILTER_BYTES:])
+		state.axisFilters[i] = NewFilter(b, PERIOD/1000.0)
 	}
-	return result
+}
+
+// Pan setting has format: mode, turns, rate (10 dps), see "Pan"
+func setPan(pan [PAN_BYTES]byte) {
+	state.panOutput = NewPan(pan, PERIOD/1000.0)
+}
+
+// Adaptive gain setting has format: flags (bit 0 enabled), still rate (dps), fast rate (dps), acceleration tolerance (% of 1g)
+func setAdaptive(adaptive [4]byte) {
+	o.SetAdaptiveGain(adaptive[0]&0x01 == 0x01, float64(adaptive[1]), float64(adaptive[2]), float64(adaptive[3])/100)
+}
+
+// Auto center setting has format: flags (bit 0 enabled), still rate (0.1 dps), still time (s), window (degrees), rate (0.1 dps)
+func setAutoCenter(autoCenter [5]byte) {
+	o.SetAutoCenter(autoCenter[0]&0x01 == 0x01, float64(autoCenter[1])/10, float64(autoCenter[2]), float64(autoCenter[3]), float64(autoCenter[4])/10)
+}
+
+// Prediction setting has format: horizon (ms), cap (degrees)
+func setPrediction(prediction [2]byte) {
+	o.SetPrediction(time.Duration(prediction[0])*time.Millisecond, float64(prediction[1]))
+}
+
+// Re-center modes setting has format: mode for button, taps (and head gestures) and command, each is one of below
+const (
+	recenterModeFull = 0 // tilt and roll are levelled at current position, fusion starts over
+	recenterModePan  = 1 // pan only, tilt and roll stay referenced to gravity
+)
+
+const (
+	recenterSourceButton  = 0
+	recenterSourceTaps    = 1
+	recenterSourceCommand = 2
+)
+
+func resetOrientation(mode byte) {
+	state.panOutput.Reset(float64(state.recenter) / 10)
+	if mode == recenterModePan {
+		o.ResetPan()
+		return
+	}
+	o.Reset()
+}
+
+// --- Button ----
+
+var buttonPressed uint16 // ms
+
+// Short press is reported on release, long press is reported once, while the button is still held
+func readButton() (short, long bool) {
+	if !pinResetCenter.Get() {
+		if buttonPressed < BUTTON_LONG {
+			buttonPressed += PERIOD
+			long = buttonPressed >= BUTTON_LONG
+		}
+		return false, long
+	}
+	short = buttonPressed > 0 && buttonPressed < BUTTON_LONG
+	buttonPressed = 0
+	return short, false
+}
+
+// --- Accelerometer calibration ----
+
+func startAccCalibration() {
+	println("Hold the head tracker still for a second in six positions, each side facing down once")
+	o.StartAccCalibration()
+	state.accCalibrating = true
+}
+
+// Indicate running calibration and store results when done
+func checkAccCalibration(iter uint16) {
+	if !state.accCalibrating {
+		return
+	}
+	if o.AccCalibrating() {
+		blinkCalibration(iter)
+		return
+	}
+	state.accCalibrating = false
+	off(ledR)
+	saveState(0)
 }
 
 // --- Display ----
@@ -282,14 +519,80 @@ func loadState() {
 		println(time.Now().Unix(), err.Error())
 	}
 
-	// set offsets, they are either actual previous calibration result or zeroes inially and in case of an error
-	o.SetOffsets(f.GyrCalOffsets()) // zeroes at worst
+	// set offsets, they are either predicted for current temperature, actual previous calibration result or zeroes inially and in case of an error
+	o.SetGyrTempModel(f.GyrTempModel())
+	o.SetOffsets(storedOffsets())   // zeroes at worst
+	o.SetMagCalibration(f.MagCal()) // ignored when zeroes
+	o.SetAccCalibration(f.AccCal()) // no calibration at worst
 
 	// set device name
 	state.deviceName = f.DeviceName()
 
 	// set axis mapping
 	state.axisMapping = f.AxisMapping()
+
+	// set fusion algorithm
+	state.fusion = f.Fusion()
+	setFusion(state.fusion)
+
+	// set board mounting
+	state.mounting = f.Mounting()
+	setMounting(state.mounting)
+
+	// set response curves and gimbal profile
+	state.curves = f.Curves()
+	state.gimbal = f.Gimbal()
+	setCurves(state.curves, state.gimbal)
+
+	// set angles decomposition
+	state.angles = f.Angles()
+	o.SetDecomposition(orientation.Decomposition(state.angles))
+
+	// set adaptive fusion gain
+	state.adaptive = f.Adaptive()
+	setAdaptive(state.adaptive)
+
+	// set head gestures
+	state.gestures = f.Gestures()
+
+	// set hold mode
+	state.hold = f.Hold()
+	state.holdOutput.Configure(state.hold)
+
+	// set re-center duration
+	state.recenter = f.Recenter()
+	o.SetRecenter(time.Duration(state.recenter) * 100 * time.Millisecond)
+
+	// set re-center modes
+	state.recenterModes = f.RecenterModes()
+
+	// set auto center
+	state.autoCenter = f.AutoCenter()
+	setAutoCenter(state.autoCenter)
+
+	// set latency compensation
+	state.prediction = f.Prediction()
+	setPrediction(state.prediction)
+
+	// set output smoothing filters
+	state.filters = f.Filters()
+	setFilters(state.filters)
+
+	// set continuous pan
+	state.pan = f.Pan()
+	setPan(state.pan)
+
+	// set PPM output
+	state.ppm = f.PPM()
+
+	// set wired output protocol
+	state.wired = f.Wired()
+
+	// set MAVLink output
+	state.mavlink = f.Mavlink()
+
+	// set gimbal controller output
+	state.controller = f.Controller()
 }
 
 // Save current configuration & calibration to flash (~85300us)
@@ -304,10 +607,32 @@ func saveState(iter uint16) {
 	defer pinDebugData.Low()
 
 	gyrCalChanged := f.SetGyrCalOffsets(o.Offsets(), flashStoreThreshold)
+	magOffsets, magRadii := o.MagCalibration()
+	magCalChanged := f.SetMagCal(magOffsets, magRadii, flashMagStoreThreshold)
+	accCalChanged := f.SetAccCal(o.AccCalibration())
+	gyrTempChanged := f.SetGyrTempModel(o.GyrTempModel(), flashGyrTempStoreThreshold)
 	deviceNameChanged := f.SetDeviceName(state.deviceName)
 	axisMappingChanged := f.SetAxisMapping(state.axisMapping)
-
-	if !gyrCalChanged && !deviceNameChanged && !axisMappingChanged {
+	fusionChanged := f.SetFusion(state.fusion)
+	mountingChanged := f.SetMounting(state.mounting)
+	curvesChanged := f.SetCurves(state.curves)
+	gimbalChanged := f.SetGimbal(state.gimbal)
+	anglesChanged := f.SetAngles(state.angles)
+	adaptiveChanged := f.SetAdaptive(state.adaptive)
+	gesturesChanged := f.SetGestures(state.gestures)
+	holdChanged := f.SetHold(state.hold)
+	recenterChanged := f.SetRecenter(state.recenter)
+	recenterModesChanged := f.SetRecenterModes(state.recenterModes)
+	autoCenterChanged := f.SetAutoCenter(state.autoCenter)
+	predictionChanged := f.SetPrediction(state.prediction)
+	filtersChanged := f.SetFilters(state.filters)
+	panChanged := f.SetPan(state.pan)
+	ppmChanged := f.SetPPM(state.ppm)
+	wiredChanged := f.SetWired(state.wired)
+	mavlinkChanged := f.SetMavlink(state.mavlink)
+	controllerChanged := f.SetController(state.controller)
+
+	if !gyrCalChanged && !gyrTempChanged && !magCalChanged && !accCalChanged && !deviceNameChanged && !axisMappingChanged && !fusionChanged && !mountingChanged && !curvesChanged && !gimbalChanged && !anglesChanged && !adaptiveChanged && !gesturesChanged && !holdChanged && !recenterChanged && !recenterModesChanged && !autoCenterChanged && !predictionChanged && !filtersChanged && !panChanged && !ppmChanged && !wiredChanged && !mavlinkChanged && !controllerChanged {
 		return
 	}
 
@@ -317,6 +642,14 @@ func saveState(iter uint16) {
 	}
 }
 
+// Gyro offsets predicted for current temperature, otherwise ones from last session
+func storedOffsets() [3]int32 {
+	if offsets, ok := o.PredictOffsets(); ok {
+		return offsets
+	}
+	return f.GyrCalOffsets()
+}
+
 func abs(v int32) int32 {
 	if v < 0 {
 		return -v
@@ -366,7 +699,18 @@ func printState(iter uint16) {
 	pinDebugData.High()
 	ch0, ch1, ch2 := state.channels[0], state.channels[1], state.channels[2]
 	cal := o.Offsets()
+	gain := int(o.Gain() * 100) // percent of base fusion gain
 	runtime.ReadMemStats(&ms)
-	println(state.deviceName, Version, "|", state.address, "| [", ch0, ",", ch1, ",", ch2, "] (", cal[0], ",", cal[1], ",", cal[2], ")", ms.HeapInuse)
+	println(state.deviceName, Version, "|", state.address, "| [", ch0, ",", ch1, ",", ch2, "] (", cal[0], ",", cal[1], ",", cal[2], ")", gain, "%", ms.HeapInuse)
 	pinDebugData.Low()
 }
+
+// Motion recording for evaluation on host, see "tools/predict"
+// Every line is "time (ms), quaternion (w, x, y, z), rates (x, y, z, dps)".
+func printMotion() {
+	if !state.recording {
+		return
+	}
+	q, r := o.Motion()
+	println(time.Now().UnixMilli(), ",", q[0], ",", q[1], ",", q[2], ",", q[3], ",", r[0], ",", r[1], ",", r[2])
+}
@@ -0,0 +1,148 @@
+package orientation
+
+// Six-position accelerometer calibration.
+//
+// Every accelerometer has bias and scale error on each of its 3 axes.
+// Uncalibrated accelerometer skews gravity direction, that in turn skews tilt and roll.
+//
+// When an axis points straight up, it reads +1g, when it points straight down, it reads -1g.
+// Having both readings for each of axes, we find "Offset" (middle point) and "Scale" (to stretch the span to 2g).
+//
+// The routine is started on request, the user then holds the head tracker still in six positions, one by one,
+// each of axes pointing up and down, in any order. Position is recorded when the device is still for "accCalBatchSize" readings.
+// The routine finishes when all six positions are recorded, or is cancelled after "accCalTimeout".
+//
+// Scale is represented in millionths, so 1_000_000 means no scaling.
+
+const (
+	accCalOne            = 1_000_000 // 1g in ug, also 1.0 scale
+	accCalBatchSize      = 50        // 1 sec of still readings per position
+	accCalStillThreshold = 3_000_000 // udps, device is still when gyroscope reads less than that on each of axes
+	accCalAxisThreshold  = 850_000   // ug, axis points up or down when reads more than that
+	accCalMaxError       = 200_000   // ug, span for an axis shall be 2g, give or take
+	accCalTimeout        = 50 * 120  // 2 min, cancel when not all positions recorded by then
+)
+
+type AccCal struct {
+	Offset [3]int32 // current calibration offsets
+	Scale  [3]int32 // current calibration scales
+
+	Running   bool    // calibration routine is running
+	Positions [6]bool // recorded positions: +X, -X, +Y, -Y, +Z, -Z
+
+	up    [3]int32 // readings of axes pointing up
+	down  [3]int32 // readings of axes pointing down
+	sum   [3]int32
+	count int32
+	total int32
+}
+
+func NewAccCal() *AccCal {
+	return &AccCal{
+		Scale: [3]int32{accCalOne, accCalOne, accCalOne},
+	}
+}
+
+// Set calibration, usually loaded from flash, zero scale means no scaling
+func (a *AccCal) Set(offset, scale [3]int32) {
+	a.Offset = offset
+	for i := range scale {
+		if scale[i] == 0 {
+			scale[i] = accCalOne
+		}
+	}
+	a.Scale = scale
+}
+
+func (a *AccCal) Get(x, y, z int32) (int32, int32, int32) {
+	return a.getAxis(0, x), a.getAxis(1, y), a.getAxis(2, z)
+}
+
+func (a *AccCal) getAxis(i, v int32) int32 {
+	return int32(int64(v-a.Offset[i]) * int64(a.Scale[i]) / accCalOne)
+}
+
+// Start calibration routine
+func (a *AccCal) Start() {
+	a.Running = true
+	a.Positions = [6]bool{}
+	a.sum = [3]int32{}
+	a.count = 0
+	a.total = 0
+}
+
+// Apply raw accelerometer and calibrated gyroscope readings, nop when calibration routine is not running
+func (a *AccCal) Apply(ax, ay, az, gx, gy, gz int32) {
+	if !a.Running {
+		return
+	}
+
+	a.total++
+	if a.total > accCalTimeout {
+		println("Accelerometer calibration timed out")
+		a.Running = false
+		return
+	}
+
+	// start over when moving
+	if abs(gx) > accCalStillThreshold || abs(gy) > accCalStillThreshold || abs(gz) > accCalStillThreshold {
+		a.sum = [3]int32{}
+		a.count = 0
+		return
+	}
+
+	a.sum[0] += ax / accCalBatchSize // divide right away to avoid integer overflow
+	a.sum[1] += ay / accCalBatchSize
+	a.sum[2] += az / accCalBatchSize
+	a.count++
+	if a.count < accCalBatchSize {
+		return
+	}
+
+	a.record()
+	a.sum = [3]int32{}
+	a.count = 0
+
+	for _, p := range a.Positions {
+		if !p {
+			return
+		}
+	}
+	a.finish()
+}
+
+// Record position, if one of axes points up or down
+func (a *AccCal) record() {
+	for i, v := range a.sum {
+		p := i * 2
+		switch {
+		case v > accCalAxisThreshold:
+			a.up[i] = v
+		case v < -accCalAxisThreshold:
+			a.down[i] = v
+			p++
+		default:
+			continue
+		}
+		a.Positions[p] = true
+		println("Accelerometer calibration, position recorded:", p)
+	}
+}
+
+// Calculate offsets and scales from recorded positions
+func (a *AccCal) finish() {
+	a.Running = false
+	offset, scale := [3]int32{}, [3]int32{}
+	for i := 0; i < 3; i++ {
+		span := a.up[i] - a.down[i]
+		if abs(span-2*accCalOne) > accCalMaxError {
+			println("Accelerometer calibration failed, axis:", i, "span:", span)
+			return
+		}
+		offset[i] = (a.up[i] + a.down[i]) / 2
+		scale[i] = int32(int64(2*accCalOne) * accCalOne / int64(span))
+	}
+	a.Offset = offset
+	a.Scale = scale
+	println("Accelerometer calibration done:", offset[0], offset[1], offset[2], "/", scale[0], scale[1], scale[2])
+}
@@ -0,0 +1,68 @@
+package orientation
+
+// Adaptive fusion gain.
+//
+// Fixed gain (e.g. "madgwickBeta") is a compromise: high gain corrects gyroscope drift fast,
+// but also lets lateral accelerations (head moving, not just turning) skew tilt and roll.
+// Gravity is trustworthy when the head is still, and not at all during fast motion or when |a| is far from 1g.
+//
+// So gain is scaled by motion state, every update:
+// - still (gyroscope rate below "StillRate" and |a| within "AccTolerance" of 1g), "adaptiveGainStill" times the base gain,
+// - moving, gain goes down linearly with rate, from the base gain to "adaptiveGainFast" times the base gain at "FastRate",
+// - fast (gyroscope rate above "FastRate" or |a| out of "AccTolerance"), "adaptiveGainFast" times the base gain.
+//
+// Gain changes smoothly, see "adaptiveGainSmoothing", so output does not twitch on state change.
+// Mahony scales its proportional gain the same way.
+
+import "math"
+
+const (
+	adaptiveGainStill     = 4.0  // x base gain, 0.1 for Madgwick, Kp 1.0 for Mahony
+	adaptiveGainFast      = 0.2  // x base gain, 0.005 for Madgwick, Kp 0.05 for Mahony
+	adaptiveGainSmoothing = 0.05 // share of target gain applied every update, ~0.4s time constant at 50Hz
+)
+
+type AdaptiveGain struct {
+	Enabled      bool
+	StillRate    float64 // dps
+	FastRate     float64 // dps
+	AccTolerance float64 // g
+
+	factor float64 // current gain factor, 1.0 means base gain
+}
+
+func NewAdaptiveGain() *AdaptiveGain {
+	return &AdaptiveGain{
+		StillRate:    5,
+		FastRate:     90,
+		AccTolerance: 0.1,
+		factor:       1,
+	}
+}
+
+// Update gain factor with gyroscope (dps) and accelerometer (g) readings in any frame, returns current factor
+func (a *AdaptiveGain) Update(gx, gy, gz, ax, ay, az float64) float64 {
+	if !a.Enabled {
+		a.factor = 1
+		return a.factor
+	}
+	rate := math.Sqrt(gx*gx + gy*gy + gz*gz)
+	acc := math.Sqrt(ax*ax + ay*ay + az*az)
+
+	target := adaptiveGainFast
+	switch {
+	case math.Abs(acc-1) > a.AccTolerance || rate >= a.FastRate:
+		// fast, keep minimal gain
+	case rate <= a.StillRate:
+		target = adaptiveGainStill
+	default:
+		target = 1 + (adaptiveGainFast-1)*(rate-a.StillRate)/(a.FastRate-a.StillRate)
+	}
+	a.factor += (target - a.factor) * adaptiveGainSmoothing
+	return a.factor
+}
+
+// Factor of current gain to the base gain
+func (a *AdaptiveGain) Factor() float64 {
+	return a.factor
+}
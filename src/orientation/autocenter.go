@@ -0,0 +1,43 @@
+package orientation
+
+// Automatic pan centering, removes slow residual gyroscope drift around vertical axis.
+//
+// When the head stays still (gyroscope rate below "StillRate") for "StillTime"
+// and pan is within "Window" around center, pan reference slowly moves towards current pan, at "Rate".
+// Looking around is never affected: head is either moving or pan is outside the window.
+// Rate shall be well below what one notices, a degree per second or less.
+
+import "math"
+
+type AutoCenter struct {
+	Enabled   bool
+	StillRate float64 // dps
+	StillTime float64 // s
+	Window    float64 // degrees
+	Rate      float64 // dps
+
+	still float64 // s, head is still for
+}
+
+func NewAutoCenter() *AutoCenter {
+	return &AutoCenter{
+		StillRate: 3,
+		StillTime: 5,
+		Window:    15,
+		Rate:      1,
+	}
+}
+
+// Update with gyroscope rate (dps, any frame) and pan (radians), dt is time since previous update (s).
+// Returns pan correction (radians) to apply.
+func (a *AutoCenter) Update(gx, gy, gz, pan, dt float64) float64 {
+	if !a.Enabled || math.Sqrt(gx*gx+gy*gy+gz*gz) > a.StillRate {
+		a.still = 0
+		return 0
+	}
+	a.still += dt
+	if a.still < a.StillTime || math.Abs(pan) > a.Window*degToRad {
+		return 0
+	}
+	return math.Copysign(math.Min(a.Rate*degToRad*dt, math.Abs(pan)), pan)
+}
@@ -0,0 +1,135 @@
+package orientation
+
+// IMU drivers.
+//
+// Board has an IMU chip on one of its I2C buses, either on-board or a breakout.
+// The chip is detected at boot by probing known addresses for WHO_AM_I register value, see "imuDrivers",
+// so the same firmware works with any supported chip, e.g. XIAO BLE Sense (LSM6DS3TR-C) and Nano 33 BLE (LSM9DS1).
+// Known chips the firmware can not drive (constructor returns nil) are skipped, see "probe".
+//
+// Drivers read data in chip axes, in units of tinygo drivers:
+// rotation in udps, acceleration in ug, temperature in m°C and magnetic field in nT.
+// Chip axes to board frame conversion is known for on-board chips only (see "boardAxes"),
+// breakout chips are used in chip axes as is, configure mounting accordingly (see "MountingRotation").
+//
+// Chips sample faster than main loop runs, so drivers keep samples in hardware FIFO for "ReadFIFO" to drain every loop.
+
+import (
+	"errors"
+
+	"tinygo.org/x/drivers"
+)
+
+var errNoIMU = errors.New("no supported IMU found")
+var errUnsupportedIMU = errors.New("IMU found, but not supported by this firmware")
+
+type Driver interface {
+	Configure() error
+	ReadRotation() (x, y, z int32, err error)
+	ReadAcceleration() (x, y, z int32, err error)
+	ReadTemperature() (t int32, err error)
+	ReadMagneticField() (x, y, z int32, err error) // errNoMagnetometer when chip has none
+	ReadTap() bool                                 // hardware double tap, false when chip has no such feature
+	ReadFIFO(samples []sample) (n int, err error)  // oldest samples first, up to len(samples), the rest stays in FIFO
+	SampleRate() float64                           // Hz, of FIFO samples
+}
+
+// Sample in chip axes, rotation in udps and acceleration in ug
+type sample struct {
+	gx, gy, gz int32
+	ax, ay, az int32
+}
+
+type driverInfo struct {
+	name      string
+	address   uint16
+	whoAmIReg byte
+	whoAmI    byte
+	new       func(bus drivers.I2C, address uint16) Driver // nil driver when the chip is known, but not supported
+}
+
+// Known chips, in probing order
+var imuDrivers = [...]driverInfo{
+	{"LSM6DS3TR-C", 0x6A, 0x0F, 0x6A, newLSM6DS3TR}, // XIAO BLE Sense
+	{"LSM6DS3TR-C", 0x6B, 0x0F, 0x6A, newLSM6DS3TR},
+	{"LSM9DS1", 0x6B, 0x0F, 0x68, newLSM9DS1}, // Nano 33 BLE
+	{"LSM9DS1", 0x6A, 0x0F, 0x68, newLSM9DS1},
+	{"LSM6DSOX", 0x6A, 0x0F, 0x6C, newLSM6DSOX},
+	{"LSM6DSOX", 0x6B, 0x0F, 0x6C, newLSM6DSOX},
+	{"BMI270", 0x68, 0x00, 0x24, newBMI270}, // Nano 33 BLE Rev2, not supported yet, see driver
+	{"BMI270", 0x69, 0x00, 0x24, newBMI270},
+	{"ICM-42688-P", 0x68, 0x75, 0x47, newICM42688},
+	{"ICM-42688-P", 0x69, 0x75, 0x47, newICM42688},
+	{"MPU-6050", 0x68, 0x75, 0x68, newMPU6050},
+	{"MPU-6050", 0x69, 0x75, 0x68, newMPU6050},
+}
+
+// Probe buses for a known chip, first supported one found wins
+// Unsupported chips are reported and skipped, so a supported breakout still works next to them.
+func probe(buses ...drivers.I2C) (Driver, string, error) {
+	buf := [2]byte{}
+	err := errNoIMU
+	for _, bus := range buses {
+		for _, info := range imuDrivers {
+			buf[0], buf[1] = info.whoAmIReg, 0x00
+			if bus.Tx(info.address, buf[0:1], buf[1:2]) != nil {
+				continue
+			}
+			if buf[1] != info.whoAmI {
+				continue
+			}
+			if d := info.new(bus, info.address); d != nil {
+				return d, info.name, nil
+			}
+			println("IMU not supported:", info.name)
+			err = errUnsupportedIMU
+		}
+	}
+	return nil, "", err
+}
+
+// --- Register access, for drivers below ----
+
+type registers struct {
+	bus     drivers.I2C
+	address uint16
+	buf     [7]byte // up to 6 bytes for read + 1 byte for the register address, avoids heap allocation
+}
+
+func (r *registers) read(reg byte, size int) ([]byte, error) {
+	r.buf[0] = reg
+	err := r.bus.Tx(r.address, r.buf[0:1], r.buf[1:1+size])
+	return r.buf[1 : 1+size], err
+}
+
+func (r *registers) readBlock(reg byte, data []byte) error {
+	r.buf[0] = reg
+	return r.bus.Tx(r.address, r.buf[0:1], data)
+}
+
+func (r *registers) write(reg, value byte) error {
+	r.buf[0], r.buf[1] = reg, value
+	return r.bus.Tx(r.address, r.buf[0:2], nil)
+}
+
+// Three int16 values, little or big endian, scaled by k
+func (r *registers) readAxes(reg byte, bigEndian bool, k int32) (x, y, z int32, err error) {
+	data, err := r.read(reg, 6)
+	if err != nil {
+		return
+	}
+	x, y, z = toAxes(data, bigEndian, k)
+	return x, y, z, nil
+}
+
+func toAxes(data []byte, bigEndian bool, k int32) (x, y, z int32) {
+	v := [3]int32{}
+	for i := range v {
+		lo, hi := data[i*2], data[i*2+1]
+		if bigEndian {
+			lo, hi = hi, lo
+		}
+		v[i] = int32(int16(uint16(hi)<<8|uint16(lo))) * k
+	}
+	return v[0], v[1], v[2]
+}
@@ -0,0 +1,160 @@
+package orientation
+
+// BMI270, no tinygo driver for it yet.
+//
+// The chip runs its features and gyroscope on an internal microcontroller that is loaded with
+// a configuration file at every power up, see "Initialization" in the datasheet.
+// The file (8kB) comes with Bosch Sensor API (BSD-3-Clause, bmi270.c, "bmi270_config_file") and is not bundled yet,
+// so the chip is detected but not supported: probing skips it (see "probe") and boot reports an unsupported IMU.
+// Putting the file bytes into "bmi270ConfigFile", along with its licence notice, enables the driver.
+//
+// Datasheet: https://www.bosch-sensortec.com/media/boschsensortec/downloads/datasheets/bst-bmi270-ds000.pdf
+
+import (
+	"errors"
+	"time"
+
+	"tinygo.org/x/drivers"
+)
+
+const (
+	BMI270_ACC_X_LSB       = 0x0C
+	BMI270_GYR_X_LSB       = 0x12
+	BMI270_INTERNAL_STATUS = 0x21
+	BMI270_TEMPERATURE_0   = 0x22
+	BMI270_FIFO_LENGTH_0   = 0x24
+	BMI270_FIFO_DATA       = 0x26
+	BMI270_ACC_CONF        = 0x40
+	BMI270_ACC_RANGE       = 0x41
+	BMI270_GYR_CONF        = 0x42
+	BMI270_GYR_RANGE       = 0x43
+	BMI270_FIFO_CONFIG_0   = 0x48
+	BMI270_FIFO_CONFIG_1   = 0x49
+	BMI270_INIT_CTRL       = 0x59
+	BMI270_INIT_ADDR_0     = 0x5B
+	BMI270_INIT_ADDR_1     = 0x5C
+	BMI270_INIT_DATA       = 0x5E
+	BMI270_PWR_CONF        = 0x7C
+	BMI270_PWR_CTRL        = 0x7D
+	BMI270_CMD             = 0x7E
+)
+
+const bmi270ChunkSize = 32 // bytes of configuration file per I2C transaction
+
+// Bosch Sensor API configuration file, see above
+var bmi270ConfigFile []byte
+
+var errBMI270Config = errors.New("bmi270: configuration file is missing or failed to load")
+
+type bmi270Driver struct {
+	regs  registers
+	chunk [1 + bmi270ChunkSize]byte
+	fifo  [12]byte // headerless frame: gyro, then accel
+}
+
+// Nil when configuration file is missing, the chip can not run without it
+func newBMI270(bus drivers.I2C, address uint16) Driver {
+	if len(bmi270ConfigFile) == 0 {
+		return nil
+	}
+	return &bmi270Driver{regs: registers{bus: bus, address: address}}
+}
+
+func (d *bmi270Driver) Configure() error {
+	if len(bmi270ConfigFile) == 0 {
+		return errBMI270Config
+	}
+
+	d.regs.write(BMI270_CMD, 0xB6) // soft reset
+	time.Sleep(2 * time.Millisecond)
+	d.regs.write(BMI270_PWR_CONF, 0x00) // advanced power save off, required for the upload
+	time.Sleep(1 * time.Millisecond)
+
+	// upload configuration file
+	d.regs.write(BMI270_INIT_CTRL, 0x00)
+	for offset := 0; offset < len(bmi270ConfigFile); offset += bmi270ChunkSize {
+		words := offset / 2
+		d.regs.write(BMI270_INIT_ADDR_0, byte(words&0x0F))
+		d.regs.write(BMI270_INIT_ADDR_1, byte(words>>4))
+		d.chunk[0] = BMI270_INIT_DATA
+		n := copy(d.chunk[1:], bmi270ConfigFile[offset:])
+		err := d.regs.bus.Tx(d.regs.address, d.chunk[:1+n], nil)
+		if err != nil {
+			return err
+		}
+	}
+	d.regs.write(BMI270_INIT_CTRL, 0x01)
+	time.Sleep(20 * time.Millisecond)
+	status, err := d.regs.read(BMI270_INTERNAL_STATUS, 1)
+	if err != nil || status[0]&0x0F != 0x01 {
+		return errBMI270Config
+	}
+
+	config := [...][2]byte{
+		{BMI270_PWR_CTRL, 0x0E},      // accel, gyro and temperature on
+		{BMI270_ACC_CONF, 0xA9},      // 200Hz, normal filter, performance mode
+		{BMI270_ACC_RANGE, 0x01},     // 4g
+		{BMI270_GYR_CONF, 0xE9},      // 200Hz, normal filter, performance mode
+		{BMI270_GYR_RANGE, 0x02},     // 500 deg/s
+		{BMI270_FIFO_CONFIG_0, 0x00}, // stream mode (oldest samples overwritten when full)
+		{BMI270_FIFO_CONFIG_1, 0xC0}, // gyro and accel in FIFO, headerless
+	}
+	for _, rv := range config {
+		err := d.regs.write(rv[0], rv[1])
+		if err != nil {
+			return err
+		}
+	}
+	time.Sleep(50 * time.Millisecond) // gyro start up
+	return nil
+}
+
+// 65.536 LSB per deg/s at 500 deg/s
+func (d *bmi270Driver) ReadRotation() (x, y, z int32, err error) {
+	return d.regs.readAxes(BMI270_GYR_X_LSB, false, 15_259)
+}
+
+// 8192 LSB per g at 4g
+func (d *bmi270Driver) ReadAcceleration() (x, y, z int32, err error) {
+	return d.regs.readAxes(BMI270_ACC_X_LSB, false, 122)
+}
+
+// temp = value/512 + 23
+func (d *bmi270Driver) ReadTemperature() (t int32, err error) {
+	data, err := d.regs.read(BMI270_TEMPERATURE_0, 2)
+	if err != nil {
+		return
+	}
+	return 23_000 + int32(int16(uint16(data[1])<<8|uint16(data[0])))*1000/512, nil
+}
+
+func (d *bmi270Driver) ReadMagneticField() (x, y, z int32, err error) {
+	return 0, 0, 0, errNoMagnetometer // BMM150 on Nano 33 BLE Rev2 is not supported yet
+}
+
+func (d *bmi270Driver) ReadTap() bool {
+	return false
+}
+
+func (d *bmi270Driver) ReadFIFO(samples []sample) (n int, err error) {
+	length, err := d.regs.read(BMI270_FIFO_LENGTH_0, 2)
+	if err != nil {
+		return 0, err
+	}
+	bytes := int(length[0]) | int(length[1]&0x3F)<<8
+	for ; bytes >= len(d.fifo) && n < len(samples); bytes -= len(d.fifo) {
+		err = d.regs.readBlock(BMI270_FIFO_DATA, d.fifo[:])
+		if err != nil {
+			return n, err
+		}
+		s := &samples[n]
+		s.gx, s.gy, s.gz = toAxes(d.fifo[0:6], false, 15_259)
+		s.ax, s.ay, s.az = toAxes(d.fifo[6:12], false, 122)
+		n++
+	}
+	return n, nil
+}
+
+func (d *bmi270Driver) SampleRate() float64 {
+	return 200
+}
@@ -0,0 +1,104 @@
+package orientation
+
+// ICM-42688-P, no tinygo driver for it yet.
+//
+// Datasheet: https://invensense.tdk.com/wp-content/uploads/2020/04/ds-000347_icm-42688-p-datasheet.pdf
+
+import (
+	"time"
+
+	"tinygo.org/x/drivers"
+)
+
+const (
+	ICM42688_FIFO_CONFIG   = 0x16
+	ICM42688_TEMP_DATA1    = 0x1D
+	ICM42688_ACCEL_DATA_X1 = 0x1F
+	ICM42688_GYRO_DATA_X1  = 0x25
+	ICM42688_FIFO_COUNTH   = 0x2E
+	ICM42688_FIFO_DATA     = 0x30
+	ICM42688_PWR_MGMT0     = 0x4E
+	ICM42688_GYRO_CONFIG0  = 0x4F
+	ICM42688_ACCEL_CONFIG0 = 0x50
+	ICM42688_FIFO_CONFIG1  = 0x5F
+)
+
+type icm42688Driver struct {
+	regs registers
+	fifo [16]byte // packet: header, accel, gyro, temperature, timestamp
+}
+
+func newICM42688(bus drivers.I2C, address uint16) Driver {
+	return &icm42688Driver{regs: registers{bus: bus, address: address}}
+}
+
+func (d *icm42688Driver) Configure() error {
+	config := [...][2]byte{
+		{ICM42688_GYRO_CONFIG0, 0x47},  // 500 deg/s, 200Hz
+		{ICM42688_ACCEL_CONFIG0, 0x47}, // 4g, 200Hz
+		{ICM42688_PWR_MGMT0, 0x0F},     // gyro and accel in low noise mode, temperature on
+		{ICM42688_FIFO_CONFIG1, 0x03},  // gyro and accel in FIFO
+		{ICM42688_FIFO_CONFIG, 0x40},   // stream mode (oldest samples overwritten when full)
+	}
+	for _, rv := range config {
+		err := d.regs.write(rv[0], rv[1])
+		if err != nil {
+			return err
+		}
+	}
+	time.Sleep(50 * time.Millisecond) // gyro needs 45ms to start
+	return nil
+}
+
+// 65.5 LSB per deg/s at 500 deg/s
+func (d *icm42688Driver) ReadRotation() (x, y, z int32, err error) {
+	return d.regs.readAxes(ICM42688_GYRO_DATA_X1, true, 15_267)
+}
+
+// 8192 LSB per g at 4g
+func (d *icm42688Driver) ReadAcceleration() (x, y, z int32, err error) {
+	return d.regs.readAxes(ICM42688_ACCEL_DATA_X1, true, 122)
+}
+
+// temp = value/132.48 + 25
+func (d *icm42688Driver) ReadTemperature() (t int32, err error) {
+	data, err := d.regs.read(ICM42688_TEMP_DATA1, 2)
+	if err != nil {
+		return
+	}
+	return 25_000 + int32(int16(uint16(data[0])<<8|uint16(data[1])))*10_000/1_325, nil
+}
+
+func (d *icm42688Driver) ReadMagneticField() (x, y, z int32, err error) {
+	return 0, 0, 0, errNoMagnetometer
+}
+
+func (d *icm42688Driver) ReadTap() bool {
+	return false
+}
+
+func (d *icm42688Driver) ReadFIFO(samples []sample) (n int, err error) {
+	count, err := d.regs.read(ICM42688_FIFO_COUNTH, 2)
+	if err != nil {
+		return 0, err
+	}
+	bytes := int(count[0])<<8 | int(count[1])
+	for ; bytes >= len(d.fifo) && n < len(samples); bytes -= len(d.fifo) {
+		err = d.regs.readBlock(ICM42688_FIFO_DATA, d.fifo[:])
+		if err != nil {
+			return n, err
+		}
+		if d.fifo[0]&0x80 != 0 { // empty
+			break
+		}
+		s := &samples[n]
+		s.ax, s.ay, s.az = toAxes(d.fifo[1:7], true, 122)
+		s.gx, s.gy, s.gz = toAxes(d.fifo[7:13], true, 15_267)
+		n++
+	}
+	return n, nil
+}
+
+func (d *icm42688Driver) SampleRate() float64 {
+	return 200
+}
@@ -0,0 +1,107 @@
+package orientation
+
+import (
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/lsm6ds3tr"
+)
+
+const (
+	TAP_SRC     = 0x1C
+	TAP_CFG     = 0x58
+	TAP_THS_6D  = 0x59
+	INT_DUR2    = 0x5A
+	WAKE_UP_THS = 0x5B
+	MD1_CFG     = 0x5E
+
+	FIFO_CTRL3       = 0x08
+	FIFO_CTRL5       = 0x0A
+	FIFO_STATUS1     = 0x3A
+	FIFO_DATA_OUT_L  = 0x3E
+	FIFO_PATTERN_LEN = 6 // words: gyro X, Y, Z, then accel X, Y, Z
+)
+
+type lsm6ds3trDriver struct {
+	*lsm6ds3tr.Device
+	regs registers
+	fifo [FIFO_PATTERN_LEN * 2]byte
+}
+
+func newLSM6DS3TR(bus drivers.I2C, address uint16) Driver {
+	d := &lsm6ds3trDriver{Device: lsm6ds3tr.New(bus), regs: registers{bus: bus, address: address}}
+	d.Address = address
+	return d
+}
+
+func (d *lsm6ds3trDriver) Configure() error {
+	err := d.Device.Configure(lsm6ds3tr.Configuration{
+		AccelRange:      lsm6ds3tr.ACCEL_4G,     // 4g
+		AccelSampleRate: lsm6ds3tr.ACCEL_SR_208, // every ~4.8ms
+		GyroRange:       lsm6ds3tr.GYRO_500DPS,  // 500 deg/s
+		GyroSampleRate:  lsm6ds3tr.GYRO_SR_208,  // every ~4.8ms
+	})
+	if err != nil {
+		return err
+	}
+
+	tapConfig := [...][2]byte{
+		{TAP_CFG, 0x8F},     // interrupts enable + tap all axes + latch (saves the state of the interrupt until register is read)
+		{TAP_THS_6D, 0x02},  // tap threshold
+		{INT_DUR2, 0x20},    // tap sensing params: duration = 16*([7:4]+1)*4.8ms, quiet = 2*([3:2]+1)*4.8ms, shock = 4*([1:0]+1)*4.8ms => 0x20 = 230.4ms, 9.6ms, 19.2ms
+		{WAKE_UP_THS, 0x80}, // enable double tap events
+		{MD1_CFG, 0x08},     // route double tap events to INT1 (requited for the latch to work)
+	}
+	for _, rv := range tapConfig {
+		d.regs.write(rv[0], rv[1])
+	}
+
+	d.regs.write(FIFO_CTRL3, 0x09) // gyro and accel in FIFO, no decimation
+	d.regs.write(FIFO_CTRL5, 0x2E) // FIFO at 208Hz, continuous mode (oldest samples overwritten when full)
+
+	return nil
+}
+
+func (d *lsm6ds3trDriver) ReadMagneticField() (x, y, z int32, err error) {
+	return 0, 0, 0, errNoMagnetometer
+}
+
+func (d *lsm6ds3trDriver) ReadTap() bool {
+	data, err := d.regs.read(TAP_SRC, 1)
+	return err == nil && data[0]&0x10 != 0
+}
+
+func (d *lsm6ds3trDriver) ReadFIFO(samples []sample) (n int, err error) {
+	status, err := d.regs.read(FIFO_STATUS1, 4)
+	if err != nil {
+		return 0, err
+	}
+	words := int(status[0]) | int(status[1]&0x07)<<8
+	pattern := int(status[2]) | int(status[3]&0x03)<<8
+
+	// realign to gyro X, when previous read was cut short by overrun
+	if pattern != 0 {
+		skip := FIFO_PATTERN_LEN - pattern
+		if words < skip {
+			return 0, nil
+		}
+		d.regs.readBlock(FIFO_DATA_OUT_L, d.fifo[:skip*2])
+		words -= skip
+	}
+
+	// read address rolls over from FIFO_DATA_OUT_H back to FIFO_DATA_OUT_L
+	for n < len(samples) && words >= FIFO_PATTERN_LEN {
+		err = d.regs.readBlock(FIFO_DATA_OUT_L, d.fifo[:])
+		if err != nil {
+			return n, err
+		}
+		s := &samples[n]
+		s.gx, s.gy, s.gz = toAxes(d.fifo[0:6], false, 17_500) // 17.5 mdps per LSB at 500 deg/s
+		s.ax, s.ay, s.az = toAxes(d.fifo[6:12], false, 122)   // 0.122 mg per LSB at 4g
+		words -= FIFO_PATTERN_LEN
+		n++
+	}
+	return n, nil
+}
+
+func (d *lsm6ds3trDriver) SampleRate() float64 {
+	return 208
+}
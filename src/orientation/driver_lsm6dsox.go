@@ -0,0 +1,84 @@
+package orientation
+
+import (
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/lsm6dsox"
+)
+
+const (
+	LSM6DSOX_FIFO_CTRL3        = 0x09
+	LSM6DSOX_FIFO_CTRL4        = 0x0A
+	LSM6DSOX_FIFO_STATUS1      = 0x3A
+	LSM6DSOX_FIFO_DATA_OUT_TAG = 0x78
+	LSM6DSOX_TAG_GYRO          = 0x01
+	LSM6DSOX_TAG_ACCEL         = 0x02
+)
+
+type lsm6dsoxDriver struct {
+	*lsm6dsox.Device
+	regs    registers
+	fifo    [7]byte // tag + 3 axes
+	pending sample  // FIFO is tagged, gyro and accel come as separate words
+	hasGyro bool
+}
+
+func newLSM6DSOX(bus drivers.I2C, address uint16) Driver {
+	d := &lsm6dsoxDriver{Device: lsm6dsox.New(bus), regs: registers{bus: bus, address: address}}
+	d.Address = address
+	return d
+}
+
+func (d *lsm6dsoxDriver) Configure() error {
+	err := d.Device.Configure(lsm6dsox.Configuration{
+		AccelRange:      lsm6dsox.ACCEL_4G,     // 4g
+		AccelSampleRate: lsm6dsox.ACCEL_SR_208, // every ~4.8ms
+		GyroRange:       lsm6dsox.GYRO_500DPS,  // 500 deg/s
+		GyroSampleRate:  lsm6dsox.GYRO_SR_208,  // every ~4.8ms
+	})
+	if err != nil {
+		return err
+	}
+	d.regs.write(LSM6DSOX_FIFO_CTRL3, 0x55) // gyro and accel in FIFO at 208Hz
+	d.regs.write(LSM6DSOX_FIFO_CTRL4, 0x06) // continuous mode (oldest samples overwritten when full)
+	return nil
+}
+
+func (d *lsm6dsoxDriver) ReadMagneticField() (x, y, z int32, err error) {
+	return 0, 0, 0, errNoMagnetometer
+}
+
+func (d *lsm6dsoxDriver) ReadTap() bool {
+	return false
+}
+
+func (d *lsm6dsoxDriver) ReadFIFO(samples []sample) (n int, err error) {
+	status, err := d.regs.read(LSM6DSOX_FIFO_STATUS1, 2)
+	if err != nil {
+		return 0, err
+	}
+	words := int(status[0]) | int(status[1]&0x03)<<8
+	for ; words > 0 && n < len(samples); words-- {
+		err = d.regs.readBlock(LSM6DSOX_FIFO_DATA_OUT_TAG, d.fifo[:])
+		if err != nil {
+			return n, err
+		}
+		switch d.fifo[0] >> 3 {
+		case LSM6DSOX_TAG_GYRO:
+			d.pending.gx, d.pending.gy, d.pending.gz = toAxes(d.fifo[1:7], false, 17_500) // 17.5 mdps per LSB at 500 deg/s
+			d.hasGyro = true
+		case LSM6DSOX_TAG_ACCEL:
+			if !d.hasGyro {
+				continue
+			}
+			d.pending.ax, d.pending.ay, d.pending.az = toAxes(d.fifo[1:7], false, 122) // 0.122 mg per LSB at 4g
+			samples[n] = d.pending
+			d.hasGyro = false
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (d *lsm6dsoxDriver) SampleRate() float64 {
+	return 208
+}
@@ -0,0 +1,81 @@
+package orientation
+
+import (
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/lsm9ds1"
+)
+
+const (
+	LSM9DS1_OUT_X_G     = 0x18
+	LSM9DS1_CTRL_REG9   = 0x23
+	LSM9DS1_OUT_X_XL    = 0x28
+	LSM9DS1_FIFO_CTRL   = 0x2E
+	LSM9DS1_FIFO_SRC    = 0x2F
+	LSM9DS1_FIFO_ENABLE = 0x02 // CTRL_REG9 bit
+)
+
+type lsm9ds1Driver struct {
+	*lsm9ds1.Device
+	regs registers
+	fifo [6]byte
+}
+
+// Magnetometer is a separate device on the same chip, at its default address
+func newLSM9DS1(bus drivers.I2C, address uint16) Driver {
+	d := &lsm9ds1Driver{Device: lsm9ds1.New(bus), regs: registers{bus: bus, address: address}}
+	d.AccelAddress = uint8(address)
+	return d
+}
+
+func (d *lsm9ds1Driver) Configure() error {
+	err := d.Device.Configure(lsm9ds1.Configuration{
+		AccelRange:      lsm9ds1.ACCEL_4G,
+		AccelSampleRate: lsm9ds1.ACCEL_SR_238,
+		GyroRange:       lsm9ds1.GYRO_500DPS,
+		GyroSampleRate:  lsm9ds1.GYRO_SR_238,
+		MagRange:        lsm9ds1.MAG_4G,
+		MagSampleRate:   lsm9ds1.MAG_SR_80,
+	})
+	if err != nil {
+		return err
+	}
+	ctrl, err := d.regs.read(LSM9DS1_CTRL_REG9, 1)
+	if err != nil {
+		return err
+	}
+	d.regs.write(LSM9DS1_CTRL_REG9, ctrl[0]|LSM9DS1_FIFO_ENABLE)
+	d.regs.write(LSM9DS1_FIFO_CTRL, 0xC0) // continuous mode (oldest samples overwritten when full)
+	return nil
+}
+
+func (d *lsm9ds1Driver) ReadTap() bool {
+	return false
+}
+
+// Every FIFO slot has both gyro and accel, reading output registers pops the slot
+func (d *lsm9ds1Driver) ReadFIFO(samples []sample) (n int, err error) {
+	src, err := d.regs.read(LSM9DS1_FIFO_SRC, 1)
+	if err != nil {
+		return 0, err
+	}
+	unread := int(src[0] & 0x3F)
+	for ; unread > 0 && n < len(samples); unread-- {
+		s := &samples[n]
+		err = d.regs.readBlock(LSM9DS1_OUT_X_G, d.fifo[:])
+		if err != nil {
+			return n, err
+		}
+		s.gx, s.gy, s.gz = toAxes(d.fifo[:], false, 17_500) // 17.5 mdps per LSB at 500 deg/s
+		err = d.regs.readBlock(LSM9DS1_OUT_X_XL, d.fifo[:])
+		if err != nil {
+			return n, err
+		}
+		s.ax, s.ay, s.az = toAxes(d.fifo[:], false, 122) // 0.122 mg per LSB at 4g
+		n++
+	}
+	return n, nil
+}
+
+func (d *lsm9ds1Driver) SampleRate() float64 {
+	return 238
+}
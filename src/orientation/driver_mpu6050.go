@@ -0,0 +1,109 @@
+package orientation
+
+// MPU-6050, driven directly since tinygo driver is fixed to ±250dps that head turns easily exceed.
+//
+// Datasheet: https://invensense.tdk.com/wp-content/uploads/2015/02/MPU-6000-Register-Map1.pdf
+
+import (
+	"time"
+
+	"tinygo.org/x/drivers"
+)
+
+const (
+	MPU6050_SMPLRT_DIV   = 0x19
+	MPU6050_CONFIG       = 0x1A
+	MPU6050_GYRO_CONFIG  = 0x1B
+	MPU6050_ACCEL_CONFIG = 0x1C
+	MPU6050_ACCEL_XOUT_H = 0x3B
+	MPU6050_TEMP_OUT_H   = 0x41
+	MPU6050_GYRO_XOUT_H  = 0x43
+	MPU6050_FIFO_EN      = 0x23
+	MPU6050_USER_CTRL    = 0x6A
+	MPU6050_PWR_MGMT_1   = 0x6B
+	MPU6050_FIFO_COUNT_H = 0x72
+	MPU6050_FIFO_R_W     = 0x74
+	MPU6050_FIFO_SIZE    = 1024 // bytes
+)
+
+type mpu6050Driver struct {
+	regs registers
+	fifo [12]byte // accel, then gyro
+}
+
+func newMPU6050(bus drivers.I2C, address uint16) Driver {
+	return &mpu6050Driver{regs: registers{bus: bus, address: address}}
+}
+
+func (d *mpu6050Driver) Configure() error {
+	config := [...][2]byte{
+		{MPU6050_PWR_MGMT_1, 0x01},   // wake up, clock from X gyro PLL
+		{MPU6050_CONFIG, 0x02},       // digital low pass filter at ~95Hz, gyro output rate 1kHz
+		{MPU6050_SMPLRT_DIV, 0x04},   // 1kHz / (1 + 4) = 200Hz
+		{MPU6050_GYRO_CONFIG, 0x08},  // 500 deg/s
+		{MPU6050_ACCEL_CONFIG, 0x08}, // 4g
+		{MPU6050_FIFO_EN, 0x78},      // gyro and accel in FIFO
+		{MPU6050_USER_CTRL, 0x44},    // FIFO enabled and reset
+	}
+	for _, rv := range config {
+		err := d.regs.write(rv[0], rv[1])
+		if err != nil {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+// 65.5 LSB per deg/s at 500 deg/s
+func (d *mpu6050Driver) ReadRotation() (x, y, z int32, err error) {
+	return d.regs.readAxes(MPU6050_GYRO_XOUT_H, true, 15_267)
+}
+
+// 8192 LSB per g at 4g
+func (d *mpu6050Driver) ReadAcceleration() (x, y, z int32, err error) {
+	return d.regs.readAxes(MPU6050_ACCEL_XOUT_H, true, 122)
+}
+
+// temp = value/340 + 36.53
+func (d *mpu6050Driver) ReadTemperature() (t int32, err error) {
+	data, err := d.regs.read(MPU6050_TEMP_OUT_H, 2)
+	if err != nil {
+		return
+	}
+	return 36_530 + int32(int16(uint16(data[0])<<8|uint16(data[1])))*1000/340, nil
+}
+
+func (d *mpu6050Driver) ReadMagneticField() (x, y, z int32, err error) {
+	return 0, 0, 0, errNoMagnetometer
+}
+
+func (d *mpu6050Driver) ReadTap() bool {
+	return false
+}
+
+func (d *mpu6050Driver) ReadFIFO(samples []sample) (n int, err error) {
+	count, err := d.regs.read(MPU6050_FIFO_COUNT_H, 2)
+	if err != nil {
+		return 0, err
+	}
+	bytes := int(count[0])<<8 | int(count[1])
+	if bytes >= MPU6050_FIFO_SIZE { // overflow, samples are misaligned, start over
+		return 0, d.regs.write(MPU6050_USER_CTRL, 0x44)
+	}
+	for ; bytes >= len(d.fifo) && n < len(samples); bytes -= len(d.fifo) {
+		err = d.regs.readBlock(MPU6050_FIFO_R_W, d.fifo[:])
+		if err != nil {
+			return n, err
+		}
+		s := &samples[n]
+		s.ax, s.ay, s.az = toAxes(d.fifo[0:6], true, 122)
+		s.gx, s.gy, s.gz = toAxes(d.fifo[6:12], true, 15_267)
+		n++
+	}
+	return n, nil
+}
+
+func (d *mpu6050Driver) SampleRate() float64 {
+	return 200
+}
@@ -0,0 +1,270 @@
+package orientation
+
+// Chip detection and raw data conversion of every driver, on a simulated I2C bus.
+// Register values are built from physical readings with datasheet sensitivities, so wrong scale, byte order or layout shows.
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+var errNack = errors.New("i2c: no device at address")
+
+// Chip on fake bus: register file with address auto increment, FIFO data registers pop queued bytes instead
+type fakeChip struct {
+	regs    [256]byte
+	streams map[byte][]byte
+}
+
+type fakeBus map[uint16]*fakeChip
+
+func (b fakeBus) Tx(addr uint16, w, r []byte) error {
+	chip, ok := b[addr]
+	if !ok {
+		return errNack
+	}
+	if len(r) == 0 {
+		copy(chip.regs[w[0]:], w[1:])
+		return nil
+	}
+	if stream, ok := chip.streams[w[0]]; ok {
+		n := copy(r, stream)
+		clear(r[n:])
+		chip.streams[w[0]] = stream[n:]
+		return nil
+	}
+	copy(r, chip.regs[w[0]:])
+	return nil
+}
+
+func newFakeChip(whoAmIReg, whoAmI byte) *fakeChip {
+	c := &fakeChip{streams: map[byte][]byte{}}
+	c.regs[whoAmIReg] = whoAmI
+	return c
+}
+
+func TestProbe(t *testing.T) {
+	for _, info := range imuDrivers {
+		t.Run(fmt.Sprintf("%s@%#x", info.name, info.address), func(t *testing.T) {
+			bus := fakeBus{info.address: newFakeChip(info.whoAmIReg, info.whoAmI)}
+			d, name, err := probe(bus)
+			if info.name == "BMI270" && len(bmi270ConfigFile) == 0 {
+				if err != errUnsupportedIMU || d != nil {
+					t.Fatalf("got %v, %q, %v, want unsupported chip", d, name, err)
+				}
+				return
+			}
+			if err != nil || d == nil || name != info.name {
+				t.Fatalf("got %v, %q, %v, want %s", d, name, err, info.name)
+			}
+		})
+	}
+
+	t.Run("nothing", func(t *testing.T) {
+		if _, _, err := probe(fakeBus{}, fakeBus{0x3C: newFakeChip(0x00, 0x00)}); err != errNoIMU {
+			t.Fatalf("got %v, want %v", err, errNoIMU)
+		}
+	})
+
+	t.Run("unknown WHO_AM_I", func(t *testing.T) {
+		if _, _, err := probe(fakeBus{0x6A: newFakeChip(0x0F, 0x69)}); err != errNoIMU {
+			t.Fatalf("got %v, want %v", err, errNoIMU)
+		}
+	})
+
+	// Nano 33 BLE Rev2 with a breakout on external bus
+	t.Run("unsupported on-board, breakout", func(t *testing.T) {
+		if len(bmi270ConfigFile) != 0 {
+			t.Skip("BMI270 is supported")
+		}
+		internal := fakeBus{0x68: newFakeChip(0x00, 0x24)}
+		external := fakeBus{0x69: newFakeChip(0x75, 0x68)}
+		if _, name, err := probe(internal, external); err != nil || name != "MPU-6050" {
+			t.Fatalf("got %q, %v, want MPU-6050", name, err)
+		}
+	})
+}
+
+// Reading in physical units, as it goes in, and as drivers shall report it
+type reading struct {
+	gyr [3]float64 // dps
+	acc [3]float64 // g
+}
+
+var driverReadings = []reading{
+	{gyr: [3]float64{100, -250, 30}, acc: [3]float64{0.5, -1, 2}},
+	{gyr: [3]float64{-499, 0.5, 0}, acc: [3]float64{-3.9, 0, 1}},
+}
+
+// Chip encoding of three axes
+type encoding struct {
+	perUnit   float64 // LSB per dps or per g
+	bigEndian bool
+}
+
+func (e encoding) bytes(v [3]float64) []byte {
+	b := make([]byte, 6)
+	for i, x := range v {
+		raw := uint16(int16(math.Round(x * e.perUnit)))
+		lo, hi := byte(raw), byte(raw>>8)
+		if e.bigEndian {
+			lo, hi = hi, lo
+		}
+		b[i*2], b[i*2+1] = lo, hi
+	}
+	return b
+}
+
+// Driver output (udps or ug) against physical values, within one LSB and 0.1% of scale error
+func checkAxes(t *testing.T, what string, got [3]int32, want [3]float64, unit, perUnit float64) {
+	t.Helper()
+	for i := range got {
+		w := want[i] * unit
+		if d := math.Abs(float64(got[i]) - w); d > unit/perUnit+math.Abs(w)*0.001 {
+			t.Errorf("%s axis %d: got %d, want %.0f", what, i, got[i], w)
+		}
+	}
+}
+
+func TestDriverFIFO(t *testing.T) {
+	lsmGyr, lsmAcc := encoding{1000 / 17.5, false}, encoding{8192, false} // 500dps, 4g
+	invGyr, invAcc := encoding{65.5, true}, encoding{8192, true}
+	boschGyr, boschAcc := encoding{65.536, false}, encoding{8192, false}
+
+	cases := []struct {
+		name     string
+		new      func(bus fakeBus) Driver
+		gyr, acc encoding
+		fill     func(chip *fakeChip, readings []reading, gyr, acc encoding)
+	}{
+		{"LSM6DS3TR-C", func(bus fakeBus) Driver { return newLSM6DS3TR(bus, 0x6A) }, lsmGyr, lsmAcc,
+			func(chip *fakeChip, readings []reading, gyr, acc encoding) {
+				var data []byte
+				for _, r := range readings {
+					data = append(append(data, gyr.bytes(r.gyr)...), acc.bytes(r.acc)...)
+				}
+				words := len(data) / 2
+				chip.regs[FIFO_STATUS1], chip.regs[FIFO_STATUS1+1] = byte(words), byte(words>>8)
+				chip.streams[FIFO_DATA_OUT_L] = data
+			}},
+		{"LSM9DS1", func(bus fakeBus) Driver { return newLSM9DS1(bus, 0x6B) }, lsmGyr, lsmAcc,
+			func(chip *fakeChip, readings []reading, gyr, acc encoding) {
+				for _, r := range readings {
+					chip.streams[LSM9DS1_OUT_X_G] = append(chip.streams[LSM9DS1_OUT_X_G], gyr.bytes(r.gyr)...)
+					chip.streams[LSM9DS1_OUT_X_XL] = append(chip.streams[LSM9DS1_OUT_X_XL], acc.bytes(r.acc)...)
+				}
+				chip.regs[LSM9DS1_FIFO_SRC] = byte(len(readings))
+			}},
+		{"LSM6DSOX", func(bus fakeBus) Driver { return newLSM6DSOX(bus, 0x6A) }, lsmGyr, lsmAcc,
+			func(chip *fakeChip, readings []reading, gyr, acc encoding) {
+				// accel first, it has no gyro to pair with and is dropped
+				data := append([]byte{LSM6DSOX_TAG_ACCEL << 3}, acc.bytes(readings[0].acc)...)
+				for _, r := range readings {
+					data = append(data, LSM6DSOX_TAG_GYRO<<3)
+					data = append(data, gyr.bytes(r.gyr)...)
+					data = append(data, LSM6DSOX_TAG_ACCEL<<3)
+					data = append(data, acc.bytes(r.acc)...)
+				}
+				words := len(data) / 7
+				chip.regs[LSM6DSOX_FIFO_STATUS1], chip.regs[LSM6DSOX_FIFO_STATUS1+1] = byte(words), byte(words>>8)
+				chip.streams[LSM6DSOX_FIFO_DATA_OUT_TAG] = data
+			}},
+		{"BMI270", func(bus fakeBus) Driver { return &bmi270Driver{regs: registers{bus: bus, address: 0x68}} }, boschGyr, boschAcc,
+			func(chip *fakeChip, readings []reading, gyr, acc encoding) {
+				var data []byte
+				for _, r := range readings {
+					data = append(append(data, gyr.bytes(r.gyr)...), acc.bytes(r.acc)...)
+				}
+				chip.regs[BMI270_FIFO_LENGTH_0], chip.regs[BMI270_FIFO_LENGTH_0+1] = byte(len(data)), byte(len(data)>>8)
+				chip.streams[BMI270_FIFO_DATA] = data
+			}},
+		{"ICM-42688-P", func(bus fakeBus) Driver { return newICM42688(bus, 0x68) }, invGyr, invAcc,
+			func(chip *fakeChip, readings []reading, gyr, acc encoding) {
+				var data []byte
+				for _, r := range readings {
+					data = append(data, 0x68) // header: accel, gyro, 16 bytes packet
+					data = append(append(data, acc.bytes(r.acc)...), gyr.bytes(r.gyr)...)
+					data = append(data, 0, 0, 0) // temperature and timestamp
+				}
+				chip.regs[ICM42688_FIFO_COUNTH], chip.regs[ICM42688_FIFO_COUNTH+1] = byte(len(data)>>8), byte(len(data))
+				chip.streams[ICM42688_FIFO_DATA] = data
+			}},
+		{"MPU-6050", func(bus fakeBus) Driver { return newMPU6050(bus, 0x68) }, invGyr, invAcc,
+			func(chip *fakeChip, readings []reading, gyr, acc encoding) {
+				var data []byte
+				for _, r := range readings {
+					data = append(append(data, acc.bytes(r.acc)...), gyr.bytes(r.gyr)...)
+				}
+				chip.regs[MPU6050_FIFO_COUNT_H], chip.regs[MPU6050_FIFO_COUNT_H+1] = byte(len(data)>>8), byte(len(data))
+				chip.streams[MPU6050_FIFO_R_W] = data
+			}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			chip := newFakeChip(0, 0)
+			c.fill(chip, driverReadings, c.gyr, c.acc)
+			d := c.new(fakeBus{0x68: chip, 0x6A: chip, 0x6B: chip})
+			samples := make([]sample, len(driverReadings)+1)
+			n, err := d.ReadFIFO(samples)
+			if err != nil || n != len(driverReadings) {
+				t.Fatalf("got %d samples, %v, want %d", n, err, len(driverReadings))
+			}
+			for i, r := range driverReadings {
+				s := samples[i]
+				checkAxes(t, "rotation", [3]int32{s.gx, s.gy, s.gz}, r.gyr, 1e6, c.gyr.perUnit)
+				checkAxes(t, "acceleration", [3]int32{s.ax, s.ay, s.az}, r.acc, 1e6, c.acc.perUnit)
+			}
+		})
+	}
+}
+
+// Chips without tinygo driver read output registers directly
+func TestDriverRegisters(t *testing.T) {
+	const celsius = 30.0
+	cases := []struct {
+		name     string
+		new      func(bus fakeBus) Driver
+		gyrReg   byte
+		accReg   byte
+		gyr, acc encoding
+		tempReg  byte
+		temp     []byte // celsius, encoded
+	}{
+		{"BMI270", func(bus fakeBus) Driver { return &bmi270Driver{regs: registers{bus: bus, address: 0x68}} }, BMI270_GYR_X_LSB, BMI270_ACC_X_LSB, encoding{65.536, false}, encoding{8192, false},
+			BMI270_TEMPERATURE_0, encoding{512, false}.bytes([3]float64{celsius - 23})[:2]},
+		{"ICM-42688-P", func(bus fakeBus) Driver { return newICM42688(bus, 0x68) }, ICM42688_GYRO_DATA_X1, ICM42688_ACCEL_DATA_X1, encoding{65.5, true}, encoding{8192, true},
+			ICM42688_TEMP_DATA1, encoding{132.48, true}.bytes([3]float64{celsius - 25})[:2]},
+		{"MPU-6050", func(bus fakeBus) Driver { return newMPU6050(bus, 0x68) }, MPU6050_GYRO_XOUT_H, MPU6050_ACCEL_XOUT_H, encoding{65.5, true}, encoding{8192, true},
+			MPU6050_TEMP_OUT_H, encoding{340, true}.bytes([3]float64{celsius - 36.53})[:2]},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			chip := newFakeChip(0, 0)
+			d := c.new(fakeBus{0x68: chip})
+			r := driverReadings[0]
+			copy(chip.regs[c.gyrReg:], c.gyr.bytes(r.gyr))
+			copy(chip.regs[c.accReg:], c.acc.bytes(r.acc))
+			copy(chip.regs[c.tempReg:], c.temp)
+
+			x, y, z, err := d.ReadRotation()
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkAxes(t, "rotation", [3]int32{x, y, z}, r.gyr, 1e6, c.gyr.perUnit)
+			x, y, z, err = d.ReadAcceleration()
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkAxes(t, "acceleration", [3]int32{x, y, z}, r.acc, 1e6, c.acc.perUnit)
+			temp, err := d.ReadTemperature()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(float64(temp)-celsius*1000) > 10 {
+				t.Errorf("temperature: got %d m°C, want %.0f", temp, celsius*1000)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package orientation
+
+// Decomposition of orientation to angles.
+//
+// Euler (Tait-Bryan) angles depend on the order rotations are applied in, and every order has gimbal lock:
+// the angle of the middle axis at ±90 degrees, where the other two angles jump.
+// Pick an order that keeps gimbal lock away from poses you use, typically the same order as your gimbal axes:
+// - ZYX, pan then roll then tilt, gimbal lock at ±90 degrees roll (default, as before),
+// - ZXY, pan then tilt then roll, gimbal lock at ±90 degrees tilt, same as pan/tilt/roll gimbals,
+// - and other four orders, for completeness.
+//
+// Orders are named outer to inner axis, head frame is X to the right (tilt), Y forward (roll) and Z up (pan).
+//
+// Swing-twist decomposition has no gimbal lock apart from head upside down:
+// pan is twist around Z axis, tilt and roll are components of the remaining swing, as rotation vector.
+
+import "math"
+
+type Decomposition byte
+
+const (
+	DecompositionZYX Decomposition = iota
+	DecompositionZXY
+	DecompositionYXZ
+	DecompositionYZX
+	DecompositionXYZ
+	DecompositionXZY
+	DecompositionSwingTwist
+)
+
+// Axes of Euler orders, outer to inner
+var eulerOrders = [...][3]int{
+	DecompositionZYX: {2, 1, 0},
+	DecompositionZXY: {2, 0, 1},
+	DecompositionYXZ: {1, 0, 2},
+	DecompositionYZX: {1, 2, 0},
+	DecompositionXYZ: {0, 1, 2},
+	DecompositionXZY: {0, 2, 1},
+}
+
+// Angles (radians) around X, Y and Z axes for a quaternion, unknown decomposition means ZYX
+func decompose(w, x, y, z float64, d Decomposition) (angles [3]float64) {
+	if d == DecompositionSwingTwist {
+		return swingTwist(w, x, y, z)
+	}
+	if int(d) >= len(eulerOrders) {
+		d = DecompositionZYX
+	}
+	i, j, k := eulerOrders[d][0], eulerOrders[d][1], eulerOrders[d][2]
+	s := 1.0 // cyclic orders (XYZ, YZX, ZXY) have positive sign
+	if (j-i+3)%3 != 1 {
+		s = -1
+	}
+	m := rotationMatrix(w, x, y, z)
+	angles[i] = math.Atan2(-s*m[j][k], m[k][k])
+	angles[j] = math.Asin(math.Max(-1, math.Min(1, s*m[i][k])))
+	angles[k] = math.Atan2(-s*m[i][j], m[i][i])
+	return
+}
+
+// Twist around Z axis (pan) and swing (tilt and roll) after it
+func swingTwist(w, x, y, z float64) (angles [3]float64) {
+	if w < 0 { // same rotation, shortest way
+		w, x, y, z = -w, -x, -y, -z
+	}
+	n := math.Sqrt(w*w + z*z)
+	if n < 1e-9 { // upside down, twist is undefined, call it tilt
+		return [3]float64{math.Pi, 0, 0}
+	}
+	tw, tz := w/n, z/n
+	angles[2] = 2 * math.Atan2(tz, tw)
+
+	// swing = twist^-1 * q, its axis is in XY plane
+	sw := tw*w + tz*z
+	sx := tw*x + tz*y
+	sy := tw*y - tz*x
+	sn := math.Sqrt(sx*sx + sy*sy)
+	if sn < 1e-9 {
+		return
+	}
+	angle := 2 * math.Atan2(sn, sw)
+	angles[0] = angle * sx / sn
+	angles[1] = angle * sy / sn
+	return
+}
+
+// Rotation matrix for a unit quaternion, row major
+func rotationMatrix(w, x, y, z float64) [3][3]float64 {
+	return [3][3]float64{
+		{1 - 2*(y*y+z*z), 2 * (x*y - w*z), 2 * (x*z + w*y)},
+		{2 * (x*y + w*z), 1 - 2*(x*x+z*z), 2 * (y*z - w*x)},
+		{2 * (x*z - w*y), 2 * (y*z + w*x), 1 - 2*(x*x+y*y)},
+	}
+}
@@ -0,0 +1,96 @@
+package orientation
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	mgl "github.com/go-gl/mathgl/mgl64"
+)
+
+var decompositions = []struct {
+	name string
+	d    Decomposition
+}{
+	{"ZYX", DecompositionZYX},
+	{"ZXY", DecompositionZXY},
+	{"YXZ", DecompositionYXZ},
+	{"YZX", DecompositionYZX},
+	{"XYZ", DecompositionXYZ},
+	{"XZY", DecompositionXZY},
+	{"swing-twist", DecompositionSwingTwist},
+}
+
+var axisVectors = [3]mgl.Vec3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+
+// Quaternion back from angles, rotations applied outer to inner
+func recompose(angles [3]float64, d Decomposition) mgl.Quat {
+	if d == DecompositionSwingTwist {
+		twist := mgl.QuatRotate(angles[2], axisVectors[2])
+		swing := mgl.Vec3{angles[0], angles[1], 0}
+		if n := swing.Len(); n > 0 {
+			return twist.Mul(mgl.QuatRotate(n, swing.Mul(1/n)))
+		}
+		return twist
+	}
+	q := mgl.QuatIdent()
+	for _, axis := range eulerOrders[d] {
+		q = q.Mul(mgl.QuatRotate(angles[axis], axisVectors[axis]))
+	}
+	return q
+}
+
+func TestDecomposeRecompose(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, dn := range decompositions {
+		d := dn.d
+		t.Run(dn.name, func(t *testing.T) {
+			for i := 0; i < 1000; i++ {
+				q := mgl.Quat{W: rnd.NormFloat64(), V: mgl.Vec3{rnd.NormFloat64(), rnd.NormFloat64(), rnd.NormFloat64()}}.Normalize()
+				angles := decompose(q.W, q.V.X(), q.V.Y(), q.V.Z(), d)
+				if d != DecompositionSwingTwist && math.Abs(angles[eulerOrders[d][1]]) > 89*degToRad {
+					continue // close to gimbal lock, outer and inner angles are not defined well
+				}
+				if r := recompose(angles, d); angleBetween(q, r) > 1e-4 { // degrees
+					t.Fatalf("%v decomposes to %v, that recomposes to %v", q, angles, r)
+				}
+			}
+		})
+	}
+}
+
+func TestSwingTwistContinuity(t *testing.T) {
+	const step = 1.0 // degrees
+	check := func(t *testing.T, what string, previous, angles [3]float64) {
+		for i := range angles {
+			// pan wraps at ±180 degrees, that is the same pose, not a jump
+			if d := math.Abs(math.Remainder(angles[i]-previous[i], 2*math.Pi)) * radToDeg; d > step+1e-9 {
+				t.Fatalf("%s: angle %d jumped %.3f° from %.3f° to %.3f°", what, i, d, previous[i]*radToDeg, angles[i]*radToDeg)
+			}
+		}
+	}
+	pose := func(pan, tilt float64) [3]float64 {
+		q := mgl.QuatRotate(pan*degToRad, axisVectors[2]).Mul(mgl.QuatRotate(tilt*degToRad, axisVectors[0]))
+		return decompose(q.W, q.V.X(), q.V.Y(), q.V.Z(), DecompositionSwingTwist)
+	}
+
+	// tilt through ±90 degrees, where Euler angles with tilt in the middle lock
+	for _, pan := range []float64{-180, -135, -90, 0, 45, 90, 179} {
+		previous := pose(pan, -120)
+		for tilt := -120 + step; tilt <= 120; tilt += step {
+			angles := pose(pan, tilt)
+			check(t, "tilt sweep", previous, angles)
+			previous = angles
+		}
+	}
+
+	// pan through ±180 degrees, at any tilt, even close to vertical
+	for _, tilt := range []float64{-89.9, -60, 0, 30, 90, 120} {
+		previous := pose(-270, tilt)
+		for pan := -270 + step; pan <= 270; pan += step {
+			angles := pose(pan, tilt)
+			check(t, "pan sweep", previous, angles)
+			previous = angles
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package orientation
+
+// Sensor fusion algorithms.
+//
+// Fusion integrates gyroscope rates and corrects the resulting orientation with accelerometer (gravity) readings.
+// All implementations share same conventions:
+// - gyroscope rates are in radians per second,
+// - accelerometer and magnetometer readings are in any units (normalised internally),
+// - resulting quaternion is [w, x, y, z] and rotates sensor frame to earth frame.
+//
+// The algorithm is selected at runtime, see "FusionKind".
+
+import (
+	"math"
+
+	"github.com/tracktum/go-ahrs"
+)
+
+type FusionKind byte
+
+const (
+	FusionMadgwick      FusionKind = 0 // default
+	FusionMahony        FusionKind = 1
+	FusionComplementary FusionKind = 2
+)
+
+// Rule of thumb: increasing beta leads to (a) faster bias corrections, (b) higher sensitiveness to lateral accelerations.
+// https://stackoverflow.com/questions/47589230/what-is-the-best-beta-value-in-madgwick-filter
+const madgwickBeta = 0.025
+
+// Proportional gain gives about the same lag as Madgwick's beta and holds tilt within ~3 degrees of 1dps gyroscope bias.
+// Integral gain is off, the library does not keep integral feedback between updates, so it would not remove bias anyway.
+const (
+	mahonyKp = 0.25
+	mahonyKi = 0
+)
+
+// Share of gravity correction applied every update, 0.01 at 50Hz means time constant of ~2 seconds.
+const complementaryAlpha = 0.01
+
+type Fusion interface {
+	// Update orientation with gyroscope (rad/s) and accelerometer readings, returns orientation quaternion
+	Update6D(gx, gy, gz, ax, ay, az float64) [4]float64
+	// Same as above, also corrects heading with magnetometer readings, magnetic north is X axis
+	Update9D(gx, gy, gz, ax, ay, az, mx, my, mz float64) [4]float64
+	// Set orientation quaternion, used on reset and when switching algorithms
+	SetQuaternions(q [4]float64)
+	// Scale correction gain by a factor of the base gain, see "AdaptiveGain"
+	SetGain(factor float64)
+	// Set rate of updates (Hz), follows chip FIFO rate
+	SetSampleFreq(freq float64)
+}
+
+// NewFusion creates fusion algorithm of given kind, unknown kinds fall back to Madgwick
+func NewFusion(kind FusionKind, sampleFreq float64) Fusion {
+	switch kind {
+	case FusionMahony:
+		return &mahony{ahrs.NewMahony(mahonyKp, mahonyKi, sampleFreq)}
+	case FusionComplementary:
+		return newComplementary(complementaryAlpha, sampleFreq)
+	default:
+		return &madgwick{ahrs.NewMadgwick(madgwickBeta, sampleFreq)}
+	}
+}
+
+// --- Madgwick ----------------------------------------------------------------
+
+type madgwick struct {
+	ahrs.Madgwick
+}
+
+// Correction step is normalised, so it is NaN when orientation agrees with accelerometer exactly,
+// gyroscope alone is the right answer then
+func (m *madgwick) Update6D(gx, gy, gz, ax, ay, az float64) [4]float64 {
+	q := m.Quaternions
+	r := m.Madgwick.Update6D(gx, gy, gz, ax, ay, az)
+	if math.IsNaN(r[0]) {
+		m.Quaternions = q
+		r = m.Madgwick.Update6D(gx, gy, gz, 0, 0, 0)
+	}
+	return r
+}
+
+// Same as above, see "Update6D"
+func (m *madgwick) Update9D(gx, gy, gz, ax, ay, az, mx, my, mz float64) [4]float64 {
+	q := m.Quaternions
+	r := m.Madgwick.Update9D(gx, gy, gz, ax, ay, az, mx, my, mz)
+	if math.IsNaN(r[0]) {
+		m.Quaternions = q
+		r = m.Madgwick.Update6D(gx, gy, gz, 0, 0, 0)
+	}
+	return r
+}
+
+func (m *madgwick) SetQuaternions(q [4]float64) {
+	m.Quaternions = q
+}
+
+// Beta is not exported, so the filter is recreated, orientation is carried over
+func (m *madgwick) SetGain(factor float64) {
+	q := m.Quaternions
+	m.Madgwick = ahrs.NewMadgwick(madgwickBeta*factor, m.SampleFreq)
+	m.Quaternions = q
+}
+
+func (m *madgwick) SetSampleFreq(freq float64) {
+	m.SampleFreq = freq
+}
+
+// --- Mahony ------------------------------------------------------------------
+
+type mahony struct {
+	ahrs.Mahony
+}
+
+func (m *mahony) SetQuaternions(q [4]float64) {
+	m.Quaternions = q
+}
+
+// Gains are not exported, so the filter is recreated, orientation is carried over
+// Proportional gain scales, integral gain is off anyway (see "mahonyKi").
+func (m *mahony) SetGain(factor float64) {
+	q := m.Quaternions
+	m.Mahony = ahrs.NewMahony(mahonyKp*factor, mahonyKi, m.SampleFreq)
+	m.Quaternions = q
+}
+
+func (m *mahony) SetSampleFreq(freq float64) {
+	m.SampleFreq = freq
+}
@@ -0,0 +1,127 @@
+package orientation
+
+// Quaternion based complementary filter.
+//
+// Gyroscope rates are integrated as is, then accelerometer pulls the orientation towards gravity.
+// Correction is done in earth frame, as a rotation around a horizontal axis only,
+// so unlike Madgwick and Mahony the filter never touches pan (heading) with gravity data.
+// Magnetometer, when used, corrects heading only, as a rotation around vertical axis.
+//
+// Adapted from "Keeping a Good Attitude: A Quaternion-Based Orientation Filter for IMUs and MARGs" (Valenti et al., 2015)
+
+import "math"
+
+type complementary struct {
+	base        float64 // alpha at gain factor 1.0 and nominal sample frequency
+	nominal     float64 // sample frequency alpha is given for
+	factor      float64
+	alpha       float64
+	SampleFreq  float64
+	Quaternions [4]float64
+}
+
+func newComplementary(alpha, sampleFreq float64) *complementary {
+	return &complementary{
+		base:        alpha,
+		nominal:     sampleFreq,
+		factor:      1,
+		alpha:       alpha,
+		SampleFreq:  sampleFreq,
+		Quaternions: [4]float64{1, 0, 0, 0},
+	}
+}
+
+func (c *complementary) SetQuaternions(q [4]float64) {
+	c.Quaternions = q
+}
+
+func (c *complementary) SetGain(factor float64) {
+	c.factor = factor
+	c.alpha = math.Min(c.base*c.factor*c.nominal/c.SampleFreq, 1)
+}
+
+// Alpha is a share per update, so it is scaled to keep the same time constant
+func (c *complementary) SetSampleFreq(freq float64) {
+	c.SampleFreq = freq
+	c.SetGain(c.factor)
+}
+
+func (c *complementary) Update9D(gx, gy, gz, ax, ay, az, mx, my, mz float64) [4]float64 {
+	c.Update6D(gx, gy, gz, ax, ay, az)
+	q0, q1, q2, q3 := c.Quaternions[0], c.Quaternions[1], c.Quaternions[2], c.Quaternions[3]
+
+	// Measured magnetic field in earth frame, horizontal part shall point along X axis
+	lx := (1-2*(q2*q2+q3*q3))*mx + 2*(q1*q2-q0*q3)*my + 2*(q1*q3+q0*q2)*mz
+	ly := 2*(q1*q2+q0*q3)*mx + (1-2*(q1*q1+q3*q3))*my + 2*(q2*q3-q0*q1)*mz
+	gamma := math.Sqrt(lx*lx + ly*ly)
+	if gamma == 0 {
+		return c.Quaternions
+	}
+
+	// Rotation around Z axis that brings measured field to X-Z plane, skipped when pointing south (undefined direction)
+	if lx/gamma > -0.9 {
+		s := math.Sqrt(2 * (1 + lx/gamma))
+		d0, d3 := s/2, -ly/gamma/s
+
+		// Apply only a fraction of it
+		d0, _, _, d3 = normalise(1-c.alpha+c.alpha*d0, 0, 0, c.alpha*d3)
+
+		// Rotate in earth frame: q = d * q
+		q0, q1, q2, q3 = d0*q0-d3*q3,
+			d0*q1-d3*q2,
+			d0*q2+d3*q1,
+			d0*q3+d3*q0
+	}
+
+	c.Quaternions[0], c.Quaternions[1], c.Quaternions[2], c.Quaternions[3] = normalise(q0, q1, q2, q3)
+	return c.Quaternions
+}
+
+func (c *complementary) Update6D(gx, gy, gz, ax, ay, az float64) [4]float64 {
+	q0, q1, q2, q3 := c.Quaternions[0], c.Quaternions[1], c.Quaternions[2], c.Quaternions[3]
+
+	// Integrate rate of change of quaternion from gyroscope
+	dt := 1 / c.SampleFreq
+	q0, q1, q2, q3 = q0+0.5*dt*(-q1*gx-q2*gy-q3*gz),
+		q1+0.5*dt*(q0*gx+q2*gz-q3*gy),
+		q2+0.5*dt*(q0*gy-q1*gz+q3*gx),
+		q3+0.5*dt*(q0*gz+q1*gy-q2*gx)
+	q0, q1, q2, q3 = normalise(q0, q1, q2, q3)
+
+	// Correct with gravity, skip when accelerometer reading is invalid
+	norm := math.Sqrt(ax*ax + ay*ay + az*az)
+	if norm > 0 {
+		ax, ay, az = ax/norm, ay/norm, az/norm
+
+		// Measured gravity in earth frame, shall be (0, 0, 1) when orientation is correct
+		ex := (1-2*(q2*q2+q3*q3))*ax + 2*(q1*q2-q0*q3)*ay + 2*(q1*q3+q0*q2)*az
+		ey := 2*(q1*q2+q0*q3)*ax + (1-2*(q1*q1+q3*q3))*ay + 2*(q2*q3-q0*q1)*az
+		ez := 2*(q1*q3-q0*q2)*ax + 2*(q2*q3+q0*q1)*ay + (1-2*(q1*q1+q2*q2))*az
+
+		// Rotation that brings measured gravity to Z axis, skipped when upside down (undefined axis)
+		if ez > -0.9 {
+			s := math.Sqrt(2 * (1 + ez))
+			d0, d1, d2 := s/2, ey/s, -ex/s
+
+			// Apply only a fraction of it (linear interpolation from identity is fine for small angles)
+			d0, d1, d2, _ = normalise(1-c.alpha+c.alpha*d0, c.alpha*d1, c.alpha*d2, 0)
+
+			// Rotate in earth frame: q = d * q
+			q0, q1, q2, q3 = d0*q0-d1*q1-d2*q2,
+				d0*q1+d1*q0+d2*q3,
+				d0*q2-d1*q3+d2*q0,
+				d0*q3+d1*q2-d2*q1
+		}
+	}
+
+	c.Quaternions[0], c.Quaternions[1], c.Quaternions[2], c.Quaternions[3] = normalise(q0, q1, q2, q3)
+	return c.Quaternions
+}
+
+func normalise(q0, q1, q2, q3 float64) (float64, float64, float64, float64) {
+	n := math.Sqrt(q0*q0 + q1*q1 + q2*q2 + q3*q3)
+	if n == 0 {
+		return 1, 0, 0, 0
+	}
+	return q0 / n, q1 / n, q2 / n, q3 / n
+}
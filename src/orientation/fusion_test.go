@@ -0,0 +1,187 @@
+package orientation
+
+// Fusion backends on synthetic gyroscope and accelerometer data, so drift and lag can be compared before flashing:
+//
+//	go test ./src/orientation -run Fusion -v
+//
+// Sensor is simulated exactly: gyroscope reads true rates plus bias, accelerometer reads gravity (1g up) in sensor frame.
+
+import (
+	"math"
+	"testing"
+
+	mgl "github.com/go-gl/mathgl/mgl64"
+)
+
+const fusionTestFreq = 100.0 // Hz
+
+var fusionKinds = []struct {
+	name string
+	kind FusionKind
+}{
+	{"madgwick", FusionMadgwick},
+	{"mahony", FusionMahony},
+	{"complementary", FusionComplementary},
+}
+
+// Feed fusion with samples of a sensor turning at constant rates (rad/s, sensor frame) from truth,
+// calls back with truth and estimate after every sample, returns final truth
+func simulateFusion(f Fusion, truth mgl.Quat, rates, bias mgl.Vec3, seconds float64, each func(t float64, truth, estimate mgl.Quat)) mgl.Quat {
+	dt := 1 / fusionTestFreq
+	step := mgl.QuatIdent()
+	if n := rates.Len(); n > 0 {
+		step = mgl.QuatRotate(n*dt, rates.Normalize())
+	}
+	for i := 1; i <= int(math.Round(seconds*fusionTestFreq)); i++ {
+		truth = truth.Mul(step).Normalize()
+		g := rates.Add(bias)
+		a := truth.Conjugate().Rotate(mgl.Vec3{0, 0, 1})
+		q := f.Update6D(g[0], g[1], g[2], a[0], a[1], a[2])
+		if each != nil {
+			each(float64(i)*dt, truth, mgl.Quat{W: q[0], V: mgl.Vec3{q[1], q[2], q[3]}})
+		}
+	}
+	return truth
+}
+
+// Angle (degrees) between two orientations
+func angleBetween(a, b mgl.Quat) float64 {
+	return 2 * math.Acos(math.Min(math.Abs(a.Dot(b)), 1)) * radToDeg
+}
+
+// Angle (degrees) between vertical axes of two orientations, heading does not count
+func tiltBetween(a, b mgl.Quat) float64 {
+	up := mgl.Vec3{0, 0, 1}
+	cos := a.Conjugate().Rotate(up).Dot(b.Conjugate().Rotate(up))
+	return math.Acos(math.Max(-1, math.Min(cos, 1))) * radToDeg
+}
+
+func TestFusionConstantRate(t *testing.T) {
+	cases := []struct {
+		name  string
+		rates mgl.Vec3 // dps
+		time  float64  // s
+		limit float64  // degrees, largest error on the way
+	}{
+		{"pan", mgl.Vec3{0, 0, 90}, 4, 0.5},
+		{"tilt", mgl.Vec3{30, 0, 0}, 2, 1},
+		{"roll", mgl.Vec3{0, 30, 0}, 2, 1},
+		{"all axes", mgl.Vec3{20, -15, 60}, 3, 1},
+	}
+	for _, fk := range fusionKinds {
+		for _, c := range cases {
+			t.Run(fk.name+"/"+c.name, func(t *testing.T) {
+				f := NewFusion(fk.kind, fusionTestFreq)
+				worst := 0.0
+				simulateFusion(f, mgl.QuatIdent(), c.rates.Mul(degToRad), mgl.Vec3{}, c.time, func(_ float64, truth, estimate mgl.Quat) {
+					worst = math.Max(worst, angleBetween(truth, estimate))
+				})
+				t.Logf("largest error %.3f°", worst)
+				if worst > c.limit {
+					t.Errorf("largest error %.3f°, want at most %.1f°", worst, c.limit)
+				}
+			})
+		}
+	}
+}
+
+func TestFusionStillWithBias(t *testing.T) {
+	const seconds = 60.0
+	bias := mgl.Vec3{0.5, -0.5, 0.5}.Mul(degToRad) // rad/s, typical residual after calibration
+	for _, fk := range fusionKinds {
+		t.Run(fk.name, func(t *testing.T) {
+			f := NewFusion(fk.kind, fusionTestFreq)
+			worstTilt, settledTilt, total := 0.0, 0.0, 0.0
+			simulateFusion(f, mgl.QuatIdent(), mgl.Vec3{}, bias, seconds, func(s float64, truth, estimate mgl.Quat) {
+				tilt := tiltBetween(truth, estimate)
+				worstTilt = math.Max(worstTilt, tilt)
+				if s <= seconds/2 {
+					settledTilt = tilt
+				}
+				total = angleBetween(truth, estimate)
+			})
+			t.Logf("largest tilt error %.3f°, at half time %.3f°, total error at the end %.3f°", worstTilt, settledTilt, total)
+			// gravity bounds tilt and roll drift, it does not grow once settled
+			if worstTilt > 3.5 {
+				t.Errorf("tilt drifted %.3f°, want at most 3.5°", worstTilt)
+			}
+			if worstTilt > settledTilt+0.1 && worstTilt > 0.1 {
+				t.Errorf("tilt kept drifting after half time, from %.3f° to %.3f°", settledTilt, worstTilt)
+			}
+			// heading is not observable without magnetometer, it may drift, but not faster than bias alone
+			if limit := bias.Len()*radToDeg*seconds + 3; total > limit {
+				t.Errorf("drifted %.3f° in total, want at most %.1f°", total, limit)
+			}
+		})
+	}
+}
+
+func TestFusionStepTilt(t *testing.T) {
+	const (
+		step    = 20.0 // degrees, initial error
+		seconds = 120.0
+	)
+	// truth is tilted, estimate starts level
+	truth := mgl.QuatRotate(step*degToRad, mgl.Vec3{1, 0, 0})
+	for _, fk := range fusionKinds {
+		t.Run(fk.name, func(t *testing.T) {
+			f := NewFusion(fk.kind, fusionTestFreq)
+			lag, settled, last := 0.0, 0.0, step
+			simulateFusion(f, truth, mgl.Vec3{}, mgl.Vec3{}, seconds, func(s float64, truth, estimate mgl.Quat) {
+				last = tiltBetween(truth, estimate)
+				if lag == 0 && last < step/math.E {
+					lag = s
+				}
+				if settled == 0 && last < 1 {
+					settled = s
+				}
+			})
+			t.Logf("lag (to 1/e of error) %.2fs, settled (under 1°) %.2fs, error at the end %.4f°", lag, settled, last)
+			if lag == 0 || settled == 0 {
+				t.Fatalf("did not converge, error at the end %.3f°", last)
+			}
+			if last > 0.1 {
+				t.Errorf("error at the end %.3f°, want at most 0.1°", last)
+			}
+			if lag > 10 {
+				t.Errorf("lag %.2fs, want at most 10s", lag)
+			}
+		})
+	}
+}
+
+func TestFusionSetGain(t *testing.T) {
+	const step = 20.0 // degrees, initial error
+	truth := mgl.QuatRotate(step*degToRad, mgl.Vec3{1, 0, 0})
+	// time (s) for tilt error to fall to 1/e of step with given gain factor
+	lag := func(kind FusionKind, factor float64) float64 {
+		f := NewFusion(kind, fusionTestFreq)
+		f.SetGain(factor)
+		lag := math.Inf(1)
+		simulateFusion(f, truth, mgl.Vec3{}, mgl.Vec3{}, 60, func(s float64, truth, estimate mgl.Quat) {
+			if math.IsInf(lag, 1) && tiltBetween(truth, estimate) < step/math.E {
+				lag = s
+			}
+		})
+		return lag
+	}
+	for _, fk := range fusionKinds {
+		t.Run(fk.name, func(t *testing.T) {
+			// gain is scaled, not fixed, see "AdaptiveGain"
+			base, high := lag(fk.kind, 1), lag(fk.kind, adaptiveGainStill)
+			t.Logf("lag %.2fs at base gain, %.2fs at %.1fx", base, high, adaptiveGainStill)
+			if !(high < base/2) {
+				t.Errorf("lag %.2fs at %.1fx gain, want well under %.2fs at base gain", high, adaptiveGainStill, base)
+			}
+			// orientation is carried over, one update of correction noise aside
+			f := NewFusion(fk.kind, fusionTestFreq)
+			f.SetQuaternions([4]float64{truth.W, truth.V[0], truth.V[1], truth.V[2]})
+			f.SetGain(adaptiveGainFast)
+			a := truth.Conjugate().Rotate(mgl.Vec3{0, 0, 1})
+			q := f.Update6D(0, 0, 0, a[0], a[1], a[2])
+			if d := angleBetween(truth, mgl.Quat{W: q[0], V: mgl.Vec3{q[1], q[2], q[3]}}); d > 0.1 {
+				t.Errorf("orientation moved %.3f° on gain change", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package orientation
+
+// Head gestures, deliberate moves that are unlikely while just looking around.
+//
+// - nod, tilt back and forth quickly ("gestureSwings" swings, each faster than "gestureRate"),
+// - shake, same for pan,
+// - tilt hold, roll head to a side beyond "gestureTiltAngle" and hold it there for "gestureTiltHold".
+//
+// A swing counts only when its axis clearly dominates the other two, see "gestureDominance",
+// and swings shall follow each other within "gestureWindow", otherwise counting starts over.
+// After a gesture is recognised, all gestures are ignored for "gestureCooldown", so a shake does not end in a nod.
+
+import "math"
+
+const (
+	gestureRate      = 120.0 // dps, slowest swing
+	gestureDominance = 2.0   // times faster than other axes
+	gestureSwings    = 3     // direction changes, e.g. down-up-down
+	gestureWindow    = 0.4   // s, longest swing
+	gestureTiltAngle = 30.0  // degrees
+	gestureTiltReset = 15.0  // degrees, tilt hold recognised again after head is back within this angle
+	gestureTiltHold  = 1.0   // s
+	gestureCooldown  = 1.0   // s
+)
+
+type Gesture byte
+
+const (
+	GestureNone Gesture = iota
+	GestureNod
+	GestureShake
+	GestureTiltLeft
+	GestureTiltRight
+)
+
+// Swings around one axis
+type swings struct {
+	dir   int     // of current swing, -1, 0 (none) or 1
+	count int     // direction changes
+	since float64 // s, since current swing started
+}
+
+func (s *swings) update(rate, others, dt float64) bool {
+	s.since += dt
+	if math.Abs(rate) > gestureRate && math.Abs(rate) > gestureDominance*others {
+		dir := 1
+		if rate < 0 {
+			dir = -1
+		}
+		if dir != s.dir {
+			s.dir = dir
+			s.count++
+			s.since = 0
+		}
+	}
+	if s.since > gestureWindow {
+		s.reset()
+	}
+	return s.count >= gestureSwings
+}
+
+func (s *swings) reset() {
+	s.dir, s.count, s.since = 0, 0, 0
+}
+
+type GestureRecognizer struct {
+	nod, shake swings
+	tilt       float64 // s, held beyond tilt angle
+	tiltArmed  bool    // head was back within reset angle since last tilt hold
+	cooldown   float64 // s, left
+}
+
+func NewGestureRecognizer() *GestureRecognizer {
+	return &GestureRecognizer{tiltArmed: true}
+}
+
+// Update recognizer with angular rates (dps) and angles (radians) in head frame, dt is time since previous update (s)
+func (g *GestureRecognizer) Update(rates, angles [3]float64, dt float64) Gesture {
+	if g.cooldown > 0 {
+		g.cooldown -= dt
+		return GestureNone
+	}
+
+	rx, ry, rz := math.Abs(rates[0]), math.Abs(rates[1]), math.Abs(rates[2])
+	nod := g.nod.update(rates[0], math.Max(ry, rz), dt) // both see every sample, so swing timers never go stale
+	shake := g.shake.update(rates[2], math.Max(rx, ry), dt)
+	gesture := GestureNone
+	switch {
+	case nod:
+		gesture = GestureNod
+	case shake:
+		gesture = GestureShake
+	}
+
+	roll := angles[1] * radToDeg
+	switch {
+	case math.Abs(roll) < gestureTiltReset:
+		g.tiltArmed = true
+		g.tilt = 0
+	case math.Abs(roll) > gestureTiltAngle && g.tiltArmed:
+		g.tilt += dt
+		if g.tilt >= gestureTiltHold {
+			g.tiltArmed = false
+			gesture = GestureTiltRight // positive roll around Y (forward) axis lowers right side
+			if roll < 0 {
+				gesture = GestureTiltLeft
+			}
+		}
+	default:
+		g.tilt = 0
+	}
+
+	if gesture != GestureNone {
+		g.nod.reset()
+		g.shake.reset()
+		g.tilt = 0
+		g.cooldown = gestureCooldown
+	}
+	return gesture
+}
@@ -23,19 +23,26 @@ package orientation
 // The calibration is good enough when latest offset correction for each of axes are small,
 // that means remaining error is small too and can not induce much drift anymore.
 // The good enough calibration is indicated by "Stable" flag.
+//
+// Offsets also follow die temperature, see "GyrTemp".
 
 const (
-	gyrCalBatchSize            = 1000                      // 1 sec on warm-up, 20 sec during regular operation
+	gyrCalBatchSize            = 1000                      // 1 sec on warm-up, ~5 sec during regular operation (every FIFO sample at 208Hz)
 	gyrCalBatchEscapeMaxCount  = gyrCalBatchSize / 100 * 3 // tolerate 3% values outside threshold
 	gyrCalForceBatchesCount    = 10                        // first 10 batches applied always, regardles of number of escapes
 	gyrCalEscapeThreshold      = 4_000_000                 // this is hardware center point precision (we can expect values in this range when stationary)
 	gyrCalStableCheckThreshold = 100_000                   // shall be small enough to eliminate axis drift while large enough to keep time of warm-up low
 	gyrCalStableCorrThreshold  = 25_000                    // when stable, corrections can be large only when induced by fast movements, so capping them
+	gyrCalTemperaturePeriod    = 50                        // read die temperature every 50 reads (~1 sec)
 )
 
 type GyrCal struct {
 	Stable bool     // reached good enough offsets at least once
 	Offset [3]int32 // current calibration offsets
+	Temp   GyrTemp  // offsets versus temperature model
+
+	temperature      int32 // last die temperature, m°C
+	temperatureKnown bool
 
 	correctionLast [3]int32
 	correctionSum  [3]int32
@@ -77,6 +84,29 @@ func (g *GyrCal) applyAxis(i, v int32) {
 
 }
 
+// SetTemperature updates die temperature (m°C) and shifts offsets along learned temperature curve
+func (g *GyrCal) SetTemperature(t int32) {
+	if g.temperatureKnown {
+		prev, okPrev := g.Temp.Predict(g.temperature)
+		next, okNext := g.Temp.Predict(t)
+		if okPrev && okNext {
+			for i := range g.Offset {
+				g.Offset[i] += next[i] - prev[i]
+			}
+		}
+	}
+	g.temperature = t
+	g.temperatureKnown = true
+}
+
+// PredictOffsets for current temperature, not ok when temperature is unknown or nothing learned yet
+func (g *GyrCal) PredictOffsets() (offsets [3]int32, ok bool) {
+	if !g.temperatureKnown {
+		return offsets, false
+	}
+	return g.Temp.Predict(g.temperature)
+}
+
 func (g *GyrCal) adjustAxisOffset(i int32) {
 	// adjust when relatively stable or first times
 	if g.countEscape[i] < gyrCalBatchEscapeMaxCount || g.countForce[i] < gyrCalForceBatchesCount {
@@ -88,6 +118,9 @@ func (g *GyrCal) adjustAxisOffset(i int32) {
 		if g.countForce[i] < gyrCalForceBatchesCount {
 			g.countForce[i]++
 		}
+		if g.Stable && g.temperatureKnown { // learn only good offsets
+			g.Temp.Learn(i, g.temperature, g.Offset[i])
+		}
 	}
 	g.correctionSum[i] = 0
 	g.countApply[i] = 0
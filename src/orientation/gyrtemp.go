@@ -0,0 +1,104 @@
+package orientation
+
+// Gyroscope bias versus temperature model.
+//
+// Gyroscope bias drifts with temperature, and head tracker warms up quickly against goggles.
+// Constant calibration (see "GyrCal") follows the drift, but slowly, one batch in several seconds.
+//
+// The model keeps learned offsets for temperature ranges ("bins"), over many sessions since it is stored in flash.
+// Every time calibration adjusts an offset while stable, the bin for current temperature learns that offset.
+// Offsets for any temperature are then predicted by linear interpolation between learned bins, that is used
+// - at boot, to start with offsets for current temperature instead of the ones saved at the end of last session,
+// - as the board warms, to shift offsets along the learned curve right away.
+//
+// Note:
+// Offsets are stored as int16 in "gyrTempUnit"s to save flash space, "gyrTempEmpty" marks a bin not learned yet.
+
+const (
+	GyrTempBins = 8 // 16..48C
+
+	gyrTempBinMin   = 16_000 // m°C, lower bound of the first bin
+	gyrTempBinWidth = 4_000  // m°C
+	gyrTempUnit     = 100    // udps
+	gyrTempEmpty    = -32768 // bin not learned yet
+	gyrTempLearnDiv = 4      // bin moves 1/4 towards new offset, averages out noise of single batch
+)
+
+type GyrTemp struct {
+	Bins [GyrTempBins][3]int16
+}
+
+func NewGyrTemp() GyrTemp {
+	g := GyrTemp{}
+	for i := range g.Bins {
+		g.Bins[i] = [3]int16{gyrTempEmpty, gyrTempEmpty, gyrTempEmpty}
+	}
+	return g
+}
+
+// Learn offset of an axis at given temperature (m°C)
+func (g *GyrTemp) Learn(axis int32, temperature, offset int32) {
+	bin := gyrTempBin(temperature)
+	if bin < 0 {
+		return
+	}
+	value := offset / gyrTempUnit
+	if value <= gyrTempEmpty || value > 32767 {
+		return
+	}
+	current := int32(g.Bins[bin][axis])
+	if current == gyrTempEmpty {
+		current = value
+	}
+	g.Bins[bin][axis] = int16(current + (value-current)/gyrTempLearnDiv)
+}
+
+// Predict offsets at given temperature (m°C), not ok when nothing learned yet
+func (g *GyrTemp) Predict(temperature int32) (offsets [3]int32, ok bool) {
+	for axis := range offsets {
+		offsets[axis], ok = g.predictAxis(axis, temperature)
+		if !ok {
+			return offsets, false
+		}
+	}
+	return offsets, true
+}
+
+func (g *GyrTemp) predictAxis(axis int, temperature int32) (int32, bool) {
+	// nearest learned bins below and above (or at) temperature
+	lo, hi := -1, -1
+	for i := range g.Bins {
+		if g.Bins[i][axis] == gyrTempEmpty {
+			continue
+		}
+		if gyrTempCenter(i) <= temperature {
+			lo = i
+		} else if hi < 0 {
+			hi = i
+		}
+	}
+	switch {
+	case lo < 0 && hi < 0:
+		return 0, false
+	case lo < 0:
+		return int32(g.Bins[hi][axis]) * gyrTempUnit, true
+	case hi < 0:
+		return int32(g.Bins[lo][axis]) * gyrTempUnit, true
+	}
+	loValue, hiValue := int32(g.Bins[lo][axis]), int32(g.Bins[hi][axis])
+	loTemp, hiTemp := gyrTempCenter(lo), gyrTempCenter(hi)
+	value := int64(loValue) + int64(hiValue-loValue)*int64(temperature-loTemp)/int64(hiTemp-loTemp)
+	return int32(value) * gyrTempUnit, true
+}
+
+func gyrTempBin(temperature int32) int {
+	bin := (temperature - gyrTempBinMin) / gyrTempBinWidth
+	if temperature < gyrTempBinMin || bin >= GyrTempBins {
+		return -1
+	}
+	return int(bin)
+}
+
+func gyrTempCenter(bin int) int32 {
+	return gyrTempBinMin + int32(bin)*gyrTempBinWidth + gyrTempBinWidth/2
+}
@@ -0,0 +1,163 @@
+package orientation
+
+// IMU reads a chip found by probing (see "Driver") and calibrates its readings.
+//
+// Main loop drains chip FIFO with "ReadSamples", so every sample taken at full chip rate is integrated.
+// Gyroscope calibration learns from every sample too, accelerometer calibration from one sample per loop,
+// to keep its pacing (see "accCalBatchSize").
+
+const imuFIFOSamples = 32 // ~150ms at 208Hz, main loop runs way more often
+
+type IMU struct {
+	device Driver
+	name   string
+	axes   axes
+	gyrCal *GyrCal
+	accCal *AccCal
+	magCal *MagCal // never used when chip has no magnetometer
+	reads  uint16  // count of reads, to read temperature less often
+
+	fifo    [imuFIFOSamples]sample
+	samples [imuFIFOSamples]Sample
+}
+
+// Sample in board frame, rotation in dps and acceleration in g
+type Sample struct {
+	Gx, Gy, Gz float64
+	Ax, Ay, Az float64
+}
+
+// Signs of chip axes in board frame, for gyroscope, accelerometer and magnetometer
+type axes struct {
+	gyr, acc, mag [3]int32
+}
+
+var chipAxes = axes{
+	gyr: [3]int32{1, 1, 1},
+	acc: [3]int32{1, 1, 1},
+	mag: [3]int32{1, 1, 1},
+}
+
+func NewIMU() *IMU {
+	return &IMU{
+		gyrCal: &GyrCal{Temp: NewGyrTemp()},
+		accCal: NewAccCal(),
+		magCal: &MagCal{},
+	}
+}
+
+func (imu *IMU) Configure() error {
+	device, name, err := probeBoard()
+	if err != nil {
+		return err
+	}
+	println("IMU:", name)
+	imu.device = device
+	imu.name = name
+	imu.axes = boardAxes(name)
+	return imu.device.Configure()
+}
+
+// Name of the chip found
+func (imu *IMU) Name() string {
+	return imu.name
+}
+
+func (imu *IMU) Read() (gx, gy, gz, ax, ay, az float64, err error) {
+	s := sample{}
+	s.gx, s.gy, s.gz, err = imu.device.ReadRotation()
+	if err != nil {
+		println(err)
+		return 0, 0, 0, 0, 0, 0, err
+	}
+	s.ax, s.ay, s.az, err = imu.device.ReadAcceleration()
+	if err != nil {
+		println(err)
+		return 0, 0, 0, 0, 0, 0, err
+	}
+	imu.readTemperature()
+	r := imu.calibrate(s, true)
+	return r.Gx, r.Gy, r.Gz, r.Ax, r.Ay, r.Az, nil
+}
+
+// ReadSamples drains chip FIFO, returns samples oldest first and time between them (seconds).
+// Falls back to a single reading when FIFO is empty, with zero time meaning "since last call".
+// Returned slice is reused by next call.
+func (imu *IMU) ReadSamples() (samples []Sample, dt float64, err error) {
+	n, err := imu.device.ReadFIFO(imu.fifo[:])
+	if err != nil {
+		println(err)
+		return nil, 0, err
+	}
+	if n == 0 {
+		s := &imu.samples[0]
+		s.Gx, s.Gy, s.Gz, s.Ax, s.Ay, s.Az, err = imu.Read()
+		if err != nil {
+			return nil, 0, err
+		}
+		return imu.samples[:1], 0, nil
+	}
+	imu.readTemperature()
+	for i := range n {
+		imu.samples[i] = imu.calibrate(imu.fifo[i], i == n-1)
+	}
+	return imu.samples[:n], 1 / imu.device.SampleRate(), nil
+}
+
+// Flush drops samples accumulated in chip FIFO
+func (imu *IMU) Flush() {
+	for {
+		n, err := imu.device.ReadFIFO(imu.fifo[:])
+		if err != nil || n < len(imu.fifo) {
+			return
+		}
+	}
+}
+
+func (imu *IMU) readTemperature() {
+	if imu.reads == 0 {
+		t, err := imu.device.ReadTemperature()
+		if err == nil {
+			imu.gyrCal.SetTemperature(t)
+		}
+	}
+	imu.reads = (imu.reads + 1) % gyrCalTemperaturePeriod
+}
+
+// Calibrated sample in board frame, gyroscope calibration learns from it, accelerometer calibration when asked to
+func (imu *IMU) calibrate(s sample, learnAcc bool) (r Sample) {
+	imu.gyrCal.Apply(s.gx, s.gy, s.gz)
+	gxi, gyi, gzi := imu.gyrCal.Get(s.gx, s.gy, s.gz)
+
+	if learnAcc {
+		imu.accCal.Apply(s.ax, s.ay, s.az, gxi, gyi, gzi)
+	}
+	axi, ayi, azi := imu.accCal.Get(s.ax, s.ay, s.az)
+
+	// chip axes to board frame, this is fixed by board layout; board to head frame is mounting, see "MountingRotation"
+	g, a := imu.axes.gyr, imu.axes.acc
+	r.Gx, r.Gy, r.Gz = float64(g[0]*gxi)/1000000, float64(g[1]*gyi)/1000000, float64(g[2]*gzi)/1000000
+	r.Ax, r.Ay, r.Az = float64(a[0]*axi)/1000000, float64(a[1]*ayi)/1000000, float64(a[2]*azi)/1000000
+	return r
+}
+
+func (imu *IMU) ReadMag() (mx, my, mz float64, err error) {
+	mxi, myi, mzi, err := imu.device.ReadMagneticField()
+	if err != nil {
+		if err != errNoMagnetometer {
+			println(err)
+		}
+		return 0, 0, 0, err
+	}
+
+	imu.magCal.Apply(mxi, myi, mzi)
+	mxi, myi, mzi = imu.magCal.Get(mxi, myi, mzi)
+
+	m := imu.axes.mag
+	mx, my, mz = float64(m[0]*mxi)/1000, float64(m[1]*myi)/1000, float64(m[2]*mzi)/1000
+	return
+}
+
+func (imu *IMU) ReadTap() (tap bool) {
+	return imu.device.ReadTap()
+}
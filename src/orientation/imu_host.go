@@ -0,0 +1,13 @@
+//go:build !xiao_ble && !nano_33_ble
+
+package orientation
+
+// Host builds, e.g. evaluation tools, have no IMU; everything else in the package works as on a board.
+
+func probeBoard() (Driver, string, error) {
+	return nil, "", errNoIMU
+}
+
+func boardAxes(name string) axes {
+	return chipAxes
+}
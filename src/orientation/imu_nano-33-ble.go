@@ -5,22 +5,16 @@ package orientation
 import (
 	"machine"
 	"time"
-
-	"tinygo.org/x/drivers/lsm9ds1"
 )
 
-type IMU struct {
-	device *lsm9ds1.Device
-	gyrCal *GyrCal
-}
-
-func NewIMU() *IMU {
-	return &IMU{
-		gyrCal: &GyrCal{},
-	}
-}
+// Probe internal bus (on-board LSM9DS1 on Nano 33 BLE, unsupported BMI270 on Rev2) and then external one (breakouts, shared with display)
+func probeBoard() (Driver, string, error) {
+	// Power up on-board sensors, see lsm9ds1 driver
+	machine.LSM_PWR.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	machine.LSM_PWR.High()
+	machine.I2C_PULLUP.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	machine.I2C_PULLUP.High()
 
-func (imu *IMU) Configure() error {
 	// Configure I2C
 	err := machine.I2C1.Configure(machine.I2CConfig{
 		Frequency: 100 * machine.KHz,
@@ -28,46 +22,32 @@ func (imu *IMU) Configure() error {
 		SCL:       machine.SCL1_PIN,
 	})
 	if err != nil {
-		return err
+		return nil, "", err
 	}
-
-	// Wait a bit
-	time.Sleep(10 * time.Millisecond)
-
-	// Configure IMU
-	imu.device = lsm9ds1.New(machine.I2C1)
-	err = imu.device.Configure(lsm9ds1.Configuration{
-		AccelRange:      lsm9ds1.ACCEL_4G,
-		AccelSampleRate: lsm9ds1.ACCEL_SR_238,
-		GyroRange:       lsm9ds1.GYRO_500DPS,
-		GyroSampleRate:  lsm9ds1.GYRO_SR_238,
-		MagRange:        lsm9ds1.MAG_4G,
-		MagSampleRate:   lsm9ds1.MAG_SR_80,
+	err = machine.I2C0.Configure(machine.I2CConfig{
+		Frequency: 400 * machine.KHz,
+		SDA:       machine.SDA0_PIN,
+		SCL:       machine.SCL0_PIN,
 	})
-
-	return err
-}
-
-func (imu *IMU) Read() (gx, gy, gz, ax, ay, az float64, err error) {
-	gxi, gyi, gzi, err := imu.device.ReadRotation()
 	if err != nil {
-		println(err)
-		return 0, 0, 0, 0, 0, 0, err
-	}
-	axi, ayi, azi, err := imu.device.ReadAcceleration()
-	if err != nil {
-		println(err)
-		return 0, 0, 0, 0, 0, 0, err
+		return nil, "", err
 	}
 
-	imu.gyrCal.Apply(gxi, gyi, gzi)
-	gxi, gyi, gzi = imu.gyrCal.Get(gxi, gyi, gzi)
+	// Wait a bit
+	time.Sleep(100 * time.Millisecond)
 
-	gx, gy, gz = float64(-gxi)/1000000, float64(gyi)/1000000, float64(gzi)/1000000
-	ax, ay, az = float64(-axi)/1000000, float64(ayi)/1000000, float64(azi)/1000000
-	return
+	return probe(machine.I2C1, machine.I2C0)
 }
 
-func (imu *IMU) ReadTap() (tap bool) {
-	return false // TODO implemented tap detection on Nano 33 BLE
+// Magnetometer X axis is opposite to accelerometer and gyroscope X axis on LSM9DS1, hence no negation there
+func boardAxes(name string) axes {
+	switch name {
+	case "LSM9DS1":
+		return axes{
+			gyr: [3]int32{-1, 1, 1},
+			acc: [3]int32{-1, 1, 1},
+			mag: [3]int32{1, 1, 1},
+		}
+	}
+	return chipAxes
 }
@@ -3,34 +3,23 @@
 package orientation
 
 import (
+	"device/nrf"
 	"machine"
 	"time"
-
-	"tinygo.org/x/drivers/lsm6ds3tr"
-)
-
-const (
-	TAP_SRC     = 0x1C
-	TAP_CFG     = 0x58
-	TAP_THS_6D  = 0x59
-	INT_DUR2    = 0x5A
-	WAKE_UP_THS = 0x5B
-	MD1_CFG     = 0x5E
 )
 
-type IMU struct {
-	device *lsm6ds3tr.Device
-	gyrCal *GyrCal
-	buf    [2]byte // buffer for reading tap source register, having it here avoids heap allocation
-}
-
-func NewIMU() *IMU {
-	return &IMU{
-		gyrCal: &GyrCal{},
-	}
-}
+// Probe internal bus (on-board LSM6DS3TR-C on XIAO BLE Sense) and then external one (breakouts, shared with display)
+func probeBoard() (Driver, string, error) {
+	// Special mode for IMU power pin on this board, see lsm6ds3tr driver.
+	// Can not use pin.Configure() directly due to special mode and 32 bit size
+	pinConfig := uint32(nrf.GPIO_PIN_CNF_DIR_Output<<nrf.GPIO_PIN_CNF_DIR_Pos) |
+		uint32(nrf.GPIO_PIN_CNF_INPUT_Disconnect<<nrf.GPIO_PIN_CNF_INPUT_Pos) |
+		uint32(nrf.GPIO_PIN_CNF_PULL_Disabled<<nrf.GPIO_PIN_CNF_PULL_Pos) |
+		uint32(nrf.GPIO_PIN_CNF_DRIVE_H0H1<<nrf.GPIO_PIN_CNF_DRIVE_Pos) |
+		uint32(nrf.GPIO_PIN_CNF_SENSE_Disabled<<nrf.GPIO_PIN_CNF_SENSE_Pos)
+	nrf.P1.PIN_CNF[8].Set(pinConfig) // LSM_PWR == P1_08
+	machine.LSM_PWR.High()
 
-func (imu *IMU) Configure() error {
 	// Configure I2C
 	err := machine.I2C1.Configure(machine.I2CConfig{
 		Frequency: 100 * machine.KHz,
@@ -38,62 +27,31 @@ func (imu *IMU) Configure() error {
 		SCL:       machine.SCL1_PIN,
 	})
 	if err != nil {
-		return err
+		return nil, "", err
 	}
-
-	// Wait a bit
-	time.Sleep(10 * time.Millisecond)
-
-	// Configure IMU
-	imu.device = lsm6ds3tr.New(machine.I2C1)
-	err = imu.device.Configure(lsm6ds3tr.Configuration{
-		AccelRange:      lsm6ds3tr.ACCEL_4G,     // 4g
-		AccelSampleRate: lsm6ds3tr.ACCEL_SR_208, // every ~4.8ms
-		GyroRange:       lsm6ds3tr.GYRO_500DPS,  // 500 deg/s
-		GyroSampleRate:  lsm6ds3tr.GYRO_SR_208,  // every ~4.8ms
+	err = machine.I2C0.Configure(machine.I2CConfig{
+		Frequency: 400 * machine.KHz,
+		SDA:       machine.SDA0_PIN,
+		SCL:       machine.SCL0_PIN,
 	})
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
-	tapConfig := map[byte]byte{
-		TAP_CFG:     0x8F, // interrupts enable + tap all axes + latch (saves the state of the interrupt until register is read)
-		TAP_THS_6D:  0x02, // tap threshold
-		INT_DUR2:    0x20, // tap sensing params: duration = 16*([7:4]+1)*4.8ms, quiet = 2*([3:2]+1)*4.8ms, shock = 4*([1:0]+1)*4.8ms => 0x20 = 230.4ms, 9.6ms, 19.2ms
-		WAKE_UP_THS: 0x80, // enable double tap events
-		MD1_CFG:     0x08, // route double tap events to INT1 (requited for the latch to work)
-	}
-
-	for reg, val := range tapConfig {
-		machine.I2C1.WriteRegister(uint8(imu.device.Address), reg, []byte{val})
-	}
+	// Wait a bit
+	time.Sleep(10 * time.Millisecond)
 
-	return nil
+	return probe(machine.I2C1, machine.I2C0)
 }
 
-func (imu *IMU) Read() (gx, gy, gz, ax, ay, az float64, err error) {
-	gxi, gyi, gzi, err := imu.device.ReadRotation()
-	if err != nil {
-		println(err)
-		return 0, 0, 0, 0, 0, 0, err
+func boardAxes(name string) axes {
+	switch name {
+	case "LSM6DS3TR-C":
+		return axes{
+			gyr: [3]int32{1, -1, -1},
+			acc: [3]int32{-1, 1, 1},
+			mag: [3]int32{1, 1, 1}, // no magnetometer on this chip
+		}
 	}
-	axi, ayi, azi, err := imu.device.ReadAcceleration()
-	if err != nil {
-		println(err)
-		return 0, 0, 0, 0, 0, 0, err
-	}
-
-	imu.gyrCal.Apply(gxi, gyi, gzi)
-	gxi, gyi, gzi = imu.gyrCal.Get(gxi, gyi, gzi)
-
-	gx, gy, gz = float64(gxi)/1000000, float64(-gyi)/1000000, float64(-gzi)/1000000
-	ax, ay, az = float64(-axi)/1000000, float64(ayi)/1000000, float64(azi)/1000000
-	return
-}
-
-func (imu *IMU) ReadTap() (tap bool) {
-	imu.buf[0] = TAP_SRC
-	imu.buf[1] = 0x00
-	machine.I2C1.Tx(imu.device.Address, imu.buf[0:1], imu.buf[1:2])
-	return imu.buf[1]&0x10 != 0
+	return chipAxes
 }
@@ -0,0 +1,98 @@
+package orientation
+
+// Continuous magnetometer calibration, zero configuration.
+//
+// Magnetometer readings are distorted by the board itself and by anything magnetic mounted next to it (goggles, fans, batteries).
+// - Hard iron distortion shifts readings by a constant vector, represented with "Offset" for each of axes.
+// - Soft iron distortion stretches readings differently along each axis, represented with "Radius" for each of axes.
+//
+// Undistorted readings of a rotating magnetometer lie on a sphere, distorted ones lie on an axis aligned ellipsoid.
+// The algorithm tracks extremes of readings on each axis, ellipsoid center is the offset and its half-widths are radii.
+// Readings are then shifted by offset and scaled by average radius over axis radius, so they lie on a sphere again.
+//
+// Note:
+// The head tracker moves a lot, so extremes are collected quickly during normal use.
+// Environment may change though (other goggles, other seat), so extremes are slowly pulled towards the center,
+// see "magCalShrinkRate", to forget old environment over time.
+// Outliers (a spike near a motor or a speaker) are limited by "magCalMaxStep" per sample.
+//
+// The calibration is good enough when each of axes have seen large enough span of values ("magCalMinRadius"),
+// this is indicated by "Stable" flag, magnetometer is not used for sensor fusion until then.
+
+const (
+	magCalMinRadius  = 7_500   // nT, head turns around and tilts enough to see at least 15uT span on each of axes
+	magCalMaxRadius  = 200_000 // nT, larger values are surely noise, Earth magnetic field is 25..65uT
+	magCalMaxStep    = 5_000   // nT, extremes can't move faster than that per sample
+	magCalShrinkRate = 65536   // every sample extremes move towards center by 1/65536 of the span, ~15 min to forget half
+)
+
+type MagCal struct {
+	Stable bool     // reached good enough coverage at least once
+	Offset [3]int32 // hard iron offsets
+	Radius [3]int32 // soft iron radii
+
+	min     [3]int32
+	max     [3]int32
+	started bool
+}
+
+// Set calibration, usually loaded from flash, extremes are restored from it
+func (m *MagCal) Set(offset, radius [3]int32) {
+	m.Offset = offset
+	m.Radius = radius
+	m.Stable = true
+	for i := 0; i < 3; i++ {
+		m.min[i] = offset[i] - radius[i]
+		m.max[i] = offset[i] + radius[i]
+		m.Stable = m.Stable && radius[i] >= magCalMinRadius
+	}
+	m.started = m.Stable
+}
+
+func (m *MagCal) Get(x, y, z int32) (int32, int32, int32) {
+	avg := (m.Radius[0] + m.Radius[1] + m.Radius[2]) / 3
+	return m.getAxis(0, x, avg), m.getAxis(1, y, avg), m.getAxis(2, z, avg)
+}
+
+func (m *MagCal) getAxis(i, v, avg int32) int32 {
+	if m.Radius[i] == 0 {
+		return v - m.Offset[i]
+	}
+	return int32(int64(v-m.Offset[i]) * int64(avg) / int64(m.Radius[i]))
+}
+
+func (m *MagCal) Apply(x, y, z int32) {
+	if !m.started {
+		m.min = [3]int32{x, y, z}
+		m.max = [3]int32{x, y, z}
+		m.started = true
+	}
+	m.applyAxis(0, x)
+	m.applyAxis(1, y)
+	m.applyAxis(2, z)
+	if m.Stable {
+		return
+	}
+	m.Stable = true
+	for i := 0; i < 3; i++ {
+		m.Stable = m.Stable && m.Radius[i] >= magCalMinRadius
+	}
+}
+
+func (m *MagCal) applyAxis(i, v int32) {
+	// extend extremes, limited by max step
+	if v > m.max[i] {
+		m.max[i] += min(v-m.max[i], magCalMaxStep)
+	}
+	if v < m.min[i] {
+		m.min[i] -= min(m.min[i]-v, magCalMaxStep)
+	}
+
+	// slowly forget
+	shrink := (m.max[i] - m.min[i]) / magCalShrinkRate
+	m.max[i] -= shrink
+	m.min[i] += shrink
+
+	m.Offset[i] = (m.max[i] + m.min[i]) / 2
+	m.Radius[i] = min((m.max[i]-m.min[i])/2, magCalMaxRadius)
+}
@@ -0,0 +1,56 @@
+package orientation
+
+// Board mounting orientation.
+//
+// Head frame is X to the right, Y forward and Z up, that is also board frame
+// when the board lays flat with USB-C facing to the right (default mounting).
+//
+// Any other right angle mounting is one of 24 orientations, encoded as "index = up * 4 + turn", where
+// - up   is board axis pointing up: 0 +Z, 1 -Z, 2 +X, 3 -X, 4 +Y, 5 -Y,
+// - turn is number of 90 degrees turns around vertical axis (counterclockwise, looking from above).
+//
+// Examples (XIAO BLE):
+// - 0  flat, USB-C facing right (default),
+// - 2  flat, USB-C facing left,
+// - 4  flat upside down (components facing goggles), USB-C facing left,
+// - 12 standing vertically, USB-C facing down, components facing right.
+//
+// Arbitrary adjustment (a board mounted at an angle) can be added on top of that, as a quaternion.
+
+import (
+	"math"
+
+	mgl "github.com/go-gl/mathgl/mgl64"
+)
+
+const MountingCount = 24
+
+var (
+	axisX = mgl.Vec3{1, 0, 0}
+	axisY = mgl.Vec3{0, 1, 0}
+	axisZ = mgl.Vec3{0, 0, 1}
+)
+
+// MountingRotation returns rotation from board to head frame for a right angle mounting, unknown index means default mounting
+func MountingRotation(index byte) mgl.Quat {
+	if index >= MountingCount {
+		return mgl.QuatIdent()
+	}
+	var up mgl.Quat
+	switch index / 4 {
+	case 0: // +Z
+		up = mgl.QuatIdent()
+	case 1: // -Z
+		up = mgl.QuatRotate(math.Pi, axisY)
+	case 2: // +X
+		up = mgl.QuatRotate(-math.Pi/2, axisY)
+	case 3: // -X
+		up = mgl.QuatRotate(math.Pi/2, axisY)
+	case 4: // +Y
+		up = mgl.QuatRotate(math.Pi/2, axisX)
+	case 5: // -Y
+		up = mgl.QuatRotate(-math.Pi/2, axisX)
+	}
+	turn := mgl.QuatRotate(float64(index%4)*math.Pi/2, axisZ)
+	return turn.Mul(up)
+}
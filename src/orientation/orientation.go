@@ -1,31 +1,66 @@
 package orientation
 
 import (
+	"errors"
 	"math"
 	"time"
 
 	mgl "github.com/go-gl/mathgl/mgl64"
-	"github.com/tracktum/go-ahrs"
 )
 
 const radToDeg = 180 / math.Pi // 57.29578
 const degToRad = 1 / radToDeg  // 0.0174533
 
-// Rule of thumb: increasing beta leads to (a) faster bias corrections, (b) higher sensitiveness to lateral accelerations.
-// https://stackoverflow.com/questions/47589230/what-is-the-best-beta-value-in-madgwick-filter
-const madgwickBeta = 0.025
+var errNoMagnetometer = errors.New("no magnetometer")
 
 type Orientation struct {
-	imu     *IMU
-	fusion  ahrs.Madgwick
-	offset  mgl.Quat
-	current mgl.Quat
+	imu        *IMU
+	fusion     Fusion
+	sampleFreq float64
+	mounting   mgl.Quat // board to head frame
+	offset     mgl.Quat
+	fused      mgl.Quat // fusion result
+	current    mgl.Quat // fusion result, relative to heading at reset
+	output     mgl.Quat // current, or on its way to it after reset
+
+	recenter     float64  // s, time output takes to reach new center after reset, 0 means instant
+	recenterLeft float64  // s
+	recenterFrom mgl.Quat // output at reset
+
+	magnetometer bool     // 9D fusion requested
+	magAligned   bool     // fusion is aligned with magnetic north
+	heading      mgl.Quat // heading at reset, relative to magnetic north in 9D mode, see "ResetPan" too
+
+	decomposition Decomposition // orientation to angles
+
+	gain *AdaptiveGain
+
+	autoCenter *AutoCenter
+	predictor  *Predictor
+
+	taps TapDetector
+	tap  TapEvent // latest, until read
+
+	rates    mgl.Vec3 // dps, head frame, latest sample
+	gestures *GestureRecognizer
+	gesture  Gesture // latest, until read
+
+	failures int // consecutive updates IMU failed to read
 }
 
 func New(imu *IMU) *Orientation {
 	return &Orientation{
-		imu:    imu,
-		offset: mgl.QuatIdent(),
+		imu:        imu,
+		mounting:   mgl.QuatIdent(),
+		offset:     mgl.QuatIdent(),
+		fused:      mgl.QuatIdent(),
+		current:    mgl.QuatIdent(),
+		output:     mgl.QuatIdent(),
+		heading:    mgl.QuatIdent(),
+		gain:       NewAdaptiveGain(),
+		gestures:   NewGestureRecognizer(),
+		autoCenter: NewAutoCenter(),
+		predictor:  NewPredictor(),
 	}
 }
 
@@ -34,23 +69,105 @@ func (o *Orientation) Configure(period time.Duration) error {
 	if err != nil {
 		return err
 	}
-	o.fusion = ahrs.NewMadgwick(madgwickBeta, float64(time.Second/period))
+	o.sampleFreq = float64(time.Second / period)
+	o.fusion = NewFusion(FusionMadgwick, o.sampleFreq)
 	return nil
 }
 
+// SetFusion replaces sensor fusion algorithm, orientation is carried over
+func (o *Orientation) SetFusion(kind FusionKind) {
+	o.fusion = NewFusion(kind, o.sampleFreq)
+	o.fusion.SetQuaternions([4]float64{o.fused.W, o.fused.V[0], o.fused.V[1], o.fused.V[2]})
+}
+
+// SetMagnetometer enables 9D fusion, magnetometer is used only when available and calibrated
+func (o *Orientation) SetMagnetometer(enabled bool) {
+	o.magnetometer = enabled
+	o.magAligned = false
+}
+
+// SetDecomposition selects how orientation is decomposed to angles, see "Decomposition"
+func (o *Orientation) SetDecomposition(d Decomposition) {
+	o.decomposition = d
+}
+
+// SetAdaptiveGain configures fusion gain adaptation to motion state, see "AdaptiveGain"
+// Rates are in dps, acceleration tolerance is in g.
+func (o *Orientation) SetAdaptiveGain(enabled bool, stillRate, fastRate, accTolerance float64) {
+	o.gain.Enabled = enabled
+	o.gain.StillRate = stillRate
+	o.gain.FastRate = max(fastRate, stillRate+1)
+	o.gain.AccTolerance = accTolerance
+}
+
+// Gain is current fusion gain, as a factor of the base gain
+func (o *Orientation) Gain() float64 {
+	return o.gain.Factor()
+}
+
+// SetRecenter sets time output takes to move to new center on reset, so a gimbal does not slam to center
+func (o *Orientation) SetRecenter(duration time.Duration) {
+	o.recenter = duration.Seconds()
+	o.recenterLeft = min(o.recenterLeft, o.recenter)
+}
+
+// SetAutoCenter configures slow pan centering when head is still, see "AutoCenter"
+// Rates are in dps, still time is in seconds and window is in degrees.
+func (o *Orientation) SetAutoCenter(enabled bool, stillRate, stillTime, window, rate float64) {
+	o.autoCenter.Enabled = enabled
+	o.autoCenter.StillRate = stillRate
+	o.autoCenter.StillTime = stillTime
+	o.autoCenter.Window = window
+	o.autoCenter.Rate = rate
+}
+
+// SetPrediction sets how far ahead output is extrapolated to compensate latency, capped at max angle (degrees), see "Predictor"
+func (o *Orientation) SetPrediction(horizon time.Duration, maxAngle float64) {
+	o.predictor.Horizon = horizon.Seconds()
+	o.predictor.MaxAngle = maxAngle
+}
+
+// SetMounting sets board mounting, one of right angle orientations (see "MountingRotation") with optional adjustment quaternion [w, x, y, z] on top.
+// Takes effect on next reset.
+func (o *Orientation) SetMounting(index byte, adjustment [4]float64) {
+	adj := mgl.Quat{W: adjustment[0], V: mgl.Vec3{adjustment[1], adjustment[2], adjustment[3]}}
+	if adj.Len() == 0 {
+		adj = mgl.QuatIdent()
+	}
+	o.mounting = adj.Normalize().Mul(MountingRotation(index))
+}
+
 // Reset orientation for sensor fusion algoritm
 // - aligns current gravitation vector with Z axis
 // - resets fusion quaternion
+// - output moves to new center smoothly, see "SetRecenter"
 func (o *Orientation) Reset() {
 	_, _, _, ax, ay, az, err := o.imu.Read()
 	if err != nil {
 		println(err.Error())
 		return
 	}
-	start := mgl.Vec3{ax, ay, az}
+	o.recenterFrom = o.output
+	o.recenterLeft = o.recenter
+	start := o.mounting.Rotate(mgl.Vec3{ax, ay, az})
 	dest := mgl.Vec3{0, 0, 1}
 	o.offset = mgl.QuatBetweenVectors(start, dest)
-	o.fusion.Quaternions = [4]float64{1, 0, 0, 0}
+	o.fused = mgl.QuatIdent()
+	o.fusion.SetQuaternions([4]float64{1, 0, 0, 0})
+	o.heading = mgl.QuatIdent()
+	o.magAligned = false
+	o.imu.Flush()
+}
+
+// ResetPan zeroes pan only, fusion keeps running, so tilt and roll stay referenced to gravity
+// - output moves to new center smoothly, see "SetRecenter"
+func (o *Orientation) ResetPan() {
+	q := o.current
+	pan := decompose(q.W, q.V.X(), q.V.Y(), q.V.Z(), o.decomposition)[2] // exact for orders with Z outer and for swing-twist
+	o.recenterFrom = o.output
+	o.recenterLeft = o.recenter
+	o.heading = mgl.QuatRotate(-pan, mgl.Vec3{0, 0, 1}).Mul(o.heading)
+	o.current = o.heading.Mul(o.fused)
 }
 
 // Calibrate gyroscope
@@ -62,33 +179,141 @@ func (o *Orientation) Calibrate() (corrections [3]int32) {
 	return o.imu.gyrCal.correctionLast
 }
 
-// Update orientation
+// Update orientation with all samples taken since last update
 func (o *Orientation) Update() {
 	// read raw data
-	gx, gy, gz, ax, ay, az, err := o.imu.Read()
+	samples, dt, err := o.imu.ReadSamples()
 	if err != nil {
 		println(err.Error())
+		o.failures++
 		return
 	}
-	// rotate raw vectors to original offset
-	a := o.offset.Rotate(mgl.Vec3{ax, ay, az})
-	g := o.offset.Rotate(mgl.Vec3{gx, gy, gz})
-	// apply fusion
-	q := o.fusion.Update6D(
-		g[0]*degToRad, g[1]*degToRad, g[2]*degToRad,
-		a[0], a[1], a[2],
-	)
-	o.current.W = q[0]
-	o.current.V = mgl.Vec3{q[1], q[2], q[3]}
+	o.failures = 0
+	freq := o.sampleFreq
+	if dt > 0 {
+		freq = 1 / dt
+	}
+	o.fusion.SetSampleFreq(freq)
+	// gain follows motion state once per update, latest sample is good enough for that
+	last := samples[len(samples)-1]
+	o.fusion.SetGain(o.gain.Update(last.Gx, last.Gy, last.Gz, last.Ax, last.Ay, last.Az))
+	// magnetometer is slower than the rest, one reading per update
+	m, magOk := o.readMag()
+	// rotate raw vectors to head frame and then to original offset
+	rotation := o.offset.Mul(o.mounting)
+	var q [4]float64
+	for _, s := range samples {
+		if tap := o.taps.Update(s.Ax, s.Ay, s.Az, 1/freq); tap != TapNone {
+			o.tap = tap
+		}
+		a := rotation.Rotate(mgl.Vec3{s.Ax, s.Ay, s.Az})
+		g := rotation.Rotate(mgl.Vec3{s.Gx, s.Gy, s.Gz})
+		o.rates = g
+		// apply fusion
+		if magOk {
+			q = o.fusion.Update9D(
+				g[0]*degToRad, g[1]*degToRad, g[2]*degToRad,
+				a[0], a[1], a[2],
+				m[0], m[1], m[2],
+			)
+		} else {
+			q = o.fusion.Update6D(
+				g[0]*degToRad, g[1]*degToRad, g[2]*degToRad,
+				a[0], a[1], a[2],
+			)
+		}
+	}
+	o.fused.W = q[0]
+	o.fused.V = mgl.Vec3{q[1], q[2], q[3]}
+	o.current = o.heading.Mul(o.fused)
+	elapsed := float64(len(samples)) / freq
+	// pull pan towards center when still
+	pan := decompose(o.current.W, o.current.V.X(), o.current.V.Y(), o.current.V.Z(), o.decomposition)[2]
+	if step := o.autoCenter.Update(o.rates[0], o.rates[1], o.rates[2], pan, elapsed); step != 0 {
+		o.heading = mgl.QuatRotate(-step, mgl.Vec3{0, 0, 1}).Mul(o.heading)
+		o.current = o.heading.Mul(o.fused)
+	}
+	// slerp output to current after reset
+	o.output = o.current
+	if o.recenterLeft > 0 {
+		o.recenterLeft = max(o.recenterLeft-elapsed, 0)
+		o.output = mgl.QuatSlerp(o.recenterFrom, o.current, 1-o.recenterLeft/o.recenter)
+	}
+	// compensate latency
+	o.output = o.predictor.Predict(o.output, o.rates, elapsed)
+	// gestures, once per update
+	if gesture := o.gestures.Update(o.rates, o.Angles(), elapsed); gesture != GestureNone {
+		o.gesture = gesture
+	}
 }
 
-// Angles in radians
+// Read magnetometer, rotated to head frame and original offset, when 9D fusion is requested and magnetometer is calibrated
+func (o *Orientation) readMag() (m mgl.Vec3, ok bool) {
+	if !o.magnetometer {
+		return m, false
+	}
+	mx, my, mz, err := o.imu.ReadMag()
+	if err != nil {
+		if err != errNoMagnetometer {
+			println(err.Error())
+		}
+		return m, false
+	}
+	if !o.imu.magCal.Stable {
+		return m, false
+	}
+	m = o.offset.Mul(o.mounting).Rotate(mgl.Vec3{mx, my, mz})
+	if !o.magAligned {
+		o.alignHeading(m)
+	}
+	return m, true
+}
+
+// Turn fusion to magnetic north at once and compensate that in heading, so output does not jump
+// Fusion would otherwise slowly pull pan towards magnetic north.
+func (o *Orientation) alignHeading(m mgl.Vec3) {
+	me := o.fused.Rotate(m)
+	if me[0] == 0 && me[1] == 0 {
+		return
+	}
+	turn := mgl.QuatRotate(-math.Atan2(me[1], me[0]), mgl.Vec3{0, 0, 1})
+	o.fused = turn.Mul(o.fused)
+	o.fusion.SetQuaternions([4]float64{o.fused.W, o.fused.V[0], o.fused.V[1], o.fused.V[2]})
+	o.heading = o.heading.Mul(turn.Conjugate())
+	o.magAligned = true
+}
+
+// Motion is current orientation quaternion [w, x, y, z] and angular rates (dps) in head frame, before re-center blending and prediction
+func (o *Orientation) Motion() (q [4]float64, rates [3]float64) {
+	return [4]float64{o.current.W, o.current.V[0], o.current.V[1], o.current.V[2]}, o.rates
+}
+
+// Output orientation (w, x, y, z), head frame, the one angles are decomposed from
+func (o *Orientation) Quaternion() (q [4]float64) {
+	return [4]float64{o.output.W, o.output.V[0], o.output.V[1], o.output.V[2]}
+}
+
+// Angles in radians, around X, Y and Z axes
 func (o *Orientation) Angles() (angles [3]float64) {
-	q := o.current
-	angles[0] = math.Atan2(2*(q.W*q.V.X()+q.V.Y()*q.V.Z()), 1-2*(q.V.X()*q.V.X()+q.V.Y()*q.V.Y()))
-	angles[1] = math.Asin(2 * (q.W*q.V.Y() - q.V.X()*q.V.Z()))
-	angles[2] = math.Atan2(2*(q.V.X()*q.V.Y()+q.W*q.V.Z()), 1-2*(q.V.Y()*q.V.Y()+q.V.Z()*q.V.Z()))
-	return
+	q := o.output
+	return decompose(q.W, q.V.X(), q.V.Y(), q.V.Z(), o.decomposition)
+}
+
+// Tap detected since last call, see "TapDetector"
+func (o *Orientation) Tap() (tap TapEvent) {
+	tap, o.tap = o.tap, TapNone
+	return tap
+}
+
+// Gesture recognised since last call, see "GestureRecognizer"
+func (o *Orientation) Gesture() (gesture Gesture) {
+	gesture, o.gesture = o.gesture, GestureNone
+	return gesture
+}
+
+// Consecutive updates IMU failed to read, output stays at last good orientation meanwhile
+func (o *Orientation) Failures() int {
+	return o.failures
 }
 
 // Stable state indicates gyroscope calibration is good
@@ -114,3 +339,48 @@ func (o *Orientation) SetOffsets(offsets [3]int32) {
 	o.imu.gyrCal.countForce[1] = gyrCalForceBatchesCount + 1
 	o.imu.gyrCal.countForce[2] = gyrCalForceBatchesCount + 1
 }
+
+// Magnetometer calibration, hard iron offsets and soft iron radii
+func (o *Orientation) MagCalibration() (offsets, radii [3]int32) {
+	return o.imu.magCal.Offset, o.imu.magCal.Radius
+}
+
+func (o *Orientation) SetMagCalibration(offsets, radii [3]int32) {
+	if radii[0] == 0 && radii[1] == 0 && radii[2] == 0 {
+		return
+	}
+	o.imu.magCal.Set(offsets, radii)
+}
+
+// Accelerometer calibration, offsets and scales (millionths)
+func (o *Orientation) AccCalibration() (offsets, scales [3]int32) {
+	return o.imu.accCal.Offset, o.imu.accCal.Scale
+}
+
+func (o *Orientation) SetAccCalibration(offsets, scales [3]int32) {
+	o.imu.accCal.Set(offsets, scales)
+}
+
+// StartAccCalibration starts six-position accelerometer calibration routine
+func (o *Orientation) StartAccCalibration() {
+	o.imu.accCal.Start()
+}
+
+// AccCalibrating indicates six-position accelerometer calibration routine is running
+func (o *Orientation) AccCalibrating() bool {
+	return o.imu.accCal.Running
+}
+
+// Gyroscope offsets versus temperature model
+func (o *Orientation) GyrTempModel() [GyrTempBins][3]int16 {
+	return o.imu.gyrCal.Temp.Bins
+}
+
+func (o *Orientation) SetGyrTempModel(bins [GyrTempBins][3]int16) {
+	o.imu.gyrCal.Temp.Bins = bins
+}
+
+// PredictOffsets predicts gyroscope offsets for current temperature, not ok when nothing learned yet
+func (o *Orientation) PredictOffsets() (offsets [3]int32, ok bool) {
+	return o.imu.gyrCal.PredictOffsets()
+}
@@ -0,0 +1,42 @@
+package orientation
+
+// Latency compensation.
+//
+// Output reaches the camera late: main loop period, trainer link (Bluetooth or PPM) and servo lag add up.
+// Predictor extrapolates orientation forward by "Horizon" along current angular rate, so the camera follows head closer.
+//
+// Extrapolation is capped at "MaxAngle" and fades out while head slows down (rate relative to its recent peak, see "predictPeakTime"),
+// so the output does not overshoot when head stops abruptly.
+//
+// See "tools/predict" for evaluation on recorded motion.
+
+import (
+	"math"
+
+	mgl "github.com/go-gl/mathgl/mgl64"
+)
+
+const predictPeakTime = 0.1 // s, time constant of peak rate decay
+
+type Predictor struct {
+	Horizon  float64 // s, 0 disables prediction
+	MaxAngle float64 // degrees
+
+	peak float64 // dps, recent peak rate
+}
+
+func NewPredictor() *Predictor {
+	return &Predictor{MaxAngle: 10}
+}
+
+// Predict orientation ahead by horizon, rates (dps) are in the frame q rotates from, dt is time since previous call (s)
+func (p *Predictor) Predict(q mgl.Quat, rates mgl.Vec3, dt float64) mgl.Quat {
+	rate := rates.Len()
+	p.peak = math.Max(rate, p.peak*math.Exp(-dt/predictPeakTime))
+	if p.Horizon <= 0 || rate < 1e-6 {
+		return q
+	}
+	fade := rate / p.peak // 1 while speeding up or steady, goes down while slowing down
+	angle := math.Min(rate*fade*p.Horizon, p.MaxAngle) * degToRad
+	return q.Mul(mgl.QuatRotate(angle, rates.Mul(1/rate)))
+}
@@ -0,0 +1,121 @@
+package orientation
+
+// Tap detection on accelerometer samples, works with any chip.
+//
+// A tap is a short shock: acceleration jumps away from its slowly tracked value ("gravity")
+// by more than "tapThreshold", returns within "tapShock" and stays calm for "tapQuiet".
+// Longer disturbances are head motion, not taps, they cancel pending taps and are ignored until calm again.
+//
+// Taps following each other within "tapWindow" make a double or a triple tap.
+// Single and double taps are reported only when the window has passed, so a triple tap never reports a double one first;
+// triple tap is reported right away.
+//
+// Chips with hardware tap detection (see "Driver.ReadTap") may still be used on top of this.
+
+import "math"
+
+const (
+	tapThreshold   = 0.5  // g, change of acceleration
+	tapShock       = 0.05 // s, longest shock
+	tapQuiet       = 0.03 // s, calm after shock
+	tapWindow      = 0.4  // s, longest time between taps of a double tap
+	tapGravityTime = 0.2  // s, time constant of acceleration tracking
+)
+
+type TapEvent byte
+
+const (
+	TapNone TapEvent = iota
+	TapSingle
+	TapDouble
+	TapTriple
+)
+
+type tapState byte
+
+const (
+	tapIdle tapState = iota
+	tapInShock
+	tapInQuiet
+	tapIgnore // motion, wait for calm
+)
+
+type TapDetector struct {
+	gravity [3]float64
+	primed  bool
+
+	state   tapState
+	elapsed float64 // s, in current state
+
+	taps  byte    // seen so far, waiting for more
+	since float64 // s, since last tap
+}
+
+// Update detector with acceleration (g) in any frame, dt is time since previous sample (s)
+func (t *TapDetector) Update(ax, ay, az, dt float64) (event TapEvent) {
+	if !t.primed {
+		t.gravity = [3]float64{ax, ay, az}
+		t.primed = true
+		return TapNone
+	}
+	dx, dy, dz := ax-t.gravity[0], ay-t.gravity[1], az-t.gravity[2]
+	shock := math.Sqrt(dx*dx+dy*dy+dz*dz) > tapThreshold
+	if !shock { // shocks shall not skew tracked value
+		k := math.Min(dt/tapGravityTime, 1)
+		t.gravity[0] += dx * k
+		t.gravity[1] += dy * k
+		t.gravity[2] += dz * k
+	}
+
+	if t.taps > 0 {
+		t.since += dt
+		if t.since > tapWindow {
+			event = TapEvent(t.taps) // single or double
+			t.taps = 0
+		}
+	}
+
+	t.elapsed += dt
+	switch t.state {
+	case tapIdle:
+		if shock {
+			t.enter(tapInShock)
+		}
+	case tapInShock:
+		switch {
+		case !shock:
+			t.enter(tapInQuiet)
+		case t.elapsed > tapShock:
+			t.enter(tapIgnore)
+		}
+	case tapInQuiet:
+		switch {
+		case shock:
+			t.enter(tapIgnore)
+		case t.elapsed >= tapQuiet:
+			t.enter(tapIdle)
+			t.taps++
+			t.since = 0
+			if t.taps == 3 {
+				t.taps = 0
+				return TapTriple
+			}
+		}
+	case tapIgnore:
+		switch {
+		case shock:
+			t.elapsed = 0
+		case t.elapsed >= tapQuiet:
+			t.enter(tapIdle)
+		}
+	}
+	return event
+}
+
+func (t *TapDetector) enter(state tapState) {
+	t.state = state
+	t.elapsed = 0
+	if state == tapIgnore { // motion cancels pending taps
+		t.taps = 0
+	}
+}
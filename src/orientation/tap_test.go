@@ -0,0 +1,77 @@
+package orientation
+
+// Tap detector on accelerometer traces from "testdata", one sample per line: ax,ay,az (g) at 208Hz, "#" starts a comment.
+// Traces are synthetic (tap ringing, head sway and sensor noise modelled), traces recorded on a board drop in the same way.
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const tapTraceRate = 208.0 // Hz
+
+func readTapTrace(t *testing.T, name string) (samples [][3]float64) {
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, ",")
+		if len(fields) != 3 {
+			t.Fatalf("%s:%d: want 3 values, got %d", name, line, len(fields))
+		}
+		var s [3]float64
+		for i, field := range fields {
+			if s[i], err = strconv.ParseFloat(strings.TrimSpace(field), 64); err != nil {
+				t.Fatalf("%s:%d: %v", name, line, err)
+			}
+		}
+		samples = append(samples, s)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return samples
+}
+
+func TestTapTraces(t *testing.T) {
+	cases := []struct {
+		trace  string
+		events []TapEvent
+	}{
+		{"tap_single.csv", []TapEvent{TapSingle}},
+		{"tap_double.csv", []TapEvent{TapDouble}},
+		{"tap_triple.csv", []TapEvent{TapTriple}},
+		{"motion_nods.csv", nil},
+		{"motion_bumps.csv", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.trace, func(t *testing.T) {
+			var detector TapDetector
+			var events []TapEvent
+			for _, s := range readTapTrace(t, c.trace) {
+				if event := detector.Update(s[0], s[1], s[2], 1/tapTraceRate); event != TapNone {
+					events = append(events, event)
+				}
+			}
+			if !slices.Equal(events, c.events) {
+				t.Errorf("got events %v, want %v", events, c.events)
+			}
+			// double tap is the trigger, nothing else may look like it
+			if doubles := slices.Index(events, TapDouble) >= 0; doubles != (c.trace == "tap_double.csv") {
+				t.Errorf("double tap detected: %v", doubles)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+// Continuous pan, for 360 degrees gimbals and simulators, pan is not limited to ±180 degrees and does not jump behind the pilot.
+//
+// Setting has 3 bytes:
+// - mode   0 pan is an angle within ±180 degrees (default),
+//          1 multi-turn, pan keeps counting across turns, divided by "turns" before response curve,
+//            so curve gain of 180 degrees means full scale at ±180*turns degrees,
+//          2 rate, pan rotation rate instead of angle, for continuous rotation servos,
+//            "rate" dps reads as 180 degrees by response curve, so with default curve that rate is full speed,
+// - turns  1-255, turns to full scale in multi-turn mode,
+// - rate   10 dps units, rate to full scale in rate mode.
+//
+// Orientation reset takes counted turns off gradually, in step with output moving to new center (see "Orientation.SetRecenter"),
+// so a multi-turn gimbal unwinds back to center at re-center pace instead of jumping; rate output stays zero meanwhile.
+
+import "math"
+
+const PAN_BYTES = 3
+
+const (
+	panModeAngle     = 0
+	panModeMultiTurn = 1
+	panModeRate      = 2
+)
+
+type Pan struct {
+	mode  byte
+	turns float64
+	rate  float64 // dps
+	dt    float64 // s
+
+	primed    bool
+	raw       float64 // radians, last pan
+	unwrapped float64 // radians, last pan, counted across turns
+	rotation  float64 // radians per second
+	turn      int     // full turns from center, last reported
+
+	unwinding bool    // counted turns are on their way out after reset
+	left      float64 // s, of re-center
+}
+
+func NewPan(b [PAN_BYTES]byte, dt float64) Pan {
+	p := Pan{
+		mode:  b[0],
+		turns: float64(max(b[1], 1)),
+		rate:  float64(max(b[2], 1)) * 10,
+		dt:    dt,
+	}
+	if p.mode > panModeRate {
+		p.mode = panModeAngle
+	}
+	return p
+}
+
+// Take counted turns off over re-center duration (s), 0 means instant
+func (p *Pan) Reset(recenter float64) {
+	p.unwinding = true
+	p.left = recenter
+}
+
+// Rate mode, output is pan rotation rate, not angle
+func (p *Pan) Rate() bool {
+	return p.mode == panModeRate
+}
+
+// Angle (radians) for response curve, given pan (radians, within ±π)
+func (p *Pan) Angle(pan float64) float64 {
+	if p.mode == panModeAngle {
+		return pan
+	}
+	if !p.primed {
+		p.raw, p.unwrapped, p.rotation = pan, pan, 0
+		p.primed = true
+	}
+	delta := math.Remainder(pan-p.raw, 2*math.Pi) // shortest way, across ±π
+	p.raw = pan
+	p.unwrapped += delta
+	p.rotation = delta / p.dt
+
+	// pan itself moves to center with orientation output, turns on top of it shrink along
+	if p.unwinding {
+		left := max(p.left-p.dt, 0)
+		turns := 0.0
+		if p.left > 0 {
+			turns = (p.unwrapped - pan) * left / p.left
+		}
+		p.unwrapped = pan + turns
+		p.left, p.unwinding = left, left > 0
+	}
+
+	if turn := int(math.Round(p.unwrapped / (2 * math.Pi))); turn != p.turn {
+		p.turn = turn
+		println("Pan turns:", turn)
+	}
+
+	if p.mode == panModeRate {
+		if p.unwinding { // re-center turn is not a head turn, continuous servo shall not follow it
+			return 0
+		}
+		return p.rotation * 180 / p.rate
+	}
+	return p.unwrapped / p.turns
+}
@@ -8,6 +8,8 @@ var (
 	pinResetCenter = machine.D2
 	pinSelectPPM   = machine.D8
 	pinOutputPPM   = machine.D10
+	uartOutput     = machine.UART0 // serial wired outputs, on UART TX pin
+	pinOutputSBUS  = machine.UART_TX_PIN
 )
 
 func initPins() {
@@ -0,0 +1,44 @@
+package protocol
+
+// CRSF, Crossfire serial protocol, as ExpressLRS receivers and backpacks and EdgeTX speak it, 420000 baud 8N1
+// https://github.com/crsf-wg/crsf/wiki
+//
+// Frame is address, length (of type, payload and CRC), type, payload and CRC8 DVB-S2 of type and payload.
+// RC_CHANNELS_PACKED payload is 16 channels of 11 bits, packed the same way as SBUS does.
+
+const (
+	CRSF_CHANNELS       = SBUS_CHANNELS
+	CRSF_RC_FRAME_BYTES = 26
+	CRSF_BAUD_RATE      = 420_000
+)
+
+const (
+	crsfAddressFlightController = 0xC8
+	crsfTypeRcChannelsPacked    = 0x16
+	crsfCrcPoly                 = 0xD5
+)
+
+// Encode channels (us) into RC_CHANNELS_PACKED frame
+func CrsfEncodeChannels(frame *[CRSF_RC_FRAME_BYTES]byte, channels *[CRSF_CHANNELS]uint16) {
+	frame[0] = crsfAddressFlightController
+	frame[1] = CRSF_RC_FRAME_BYTES - 2
+	frame[2] = crsfTypeRcChannelsPacked
+	packChannels(frame[3:25], channels)
+	frame[25] = Crc8DvbS2(frame[2:25])
+}
+
+// CRC8 DVB-S2, polynomial 0xD5, initial value 0, not reflected
+func Crc8DvbS2(data []byte) byte {
+	crc := byte(0)
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ crsfCrcPoly
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,43 @@
+package protocol
+
+import "testing"
+
+func TestCrc8DvbS2(t *testing.T) {
+	cases := []struct {
+		data string
+		want byte
+	}{
+		{"", 0x00},
+		{"123456789", 0xBC}, // check value
+	}
+	for _, c := range cases {
+		if got := Crc8DvbS2([]byte(c.data)); got != c.want {
+			t.Errorf("Crc8DvbS2(%q) = %#02X, want %#02X", c.data, got, c.want)
+		}
+	}
+}
+
+func TestCrsfEncodeChannels(t *testing.T) {
+	cases := []struct {
+		name     string
+		channels map[int]uint16 // the rest are centered
+		want     string
+	}{
+		{"center", nil, "C8 18 16 E0 03 1F F8 C0 07 3E F0 81 0F 7C E0 03 1F F8 C0 07 3E F0 81 0F 7C AD"},
+		{"first low, last high", map[int]uint16{0: 988, 15: 2012}, "C8 18 16 AC 00 1F F8 C0 07 3E F0 81 0F 7C E0 03 1F F8 C0 07 3E F0 81 6F E2 8C"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var frame [CRSF_RC_FRAME_BYTES]byte
+			var channels [CRSF_CHANNELS]uint16
+			for i := range channels {
+				channels[i] = 1500
+			}
+			for i, v := range c.channels {
+				channels[i] = v
+			}
+			CrsfEncodeChannels(&frame, &channels)
+			checkFrame(t, frame[:], fromHex(t, c.want))
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package protocol
+
+// MAVLink v2, gimbal control messages
+// https://mavlink.io/en/guide/serialization.html
+// https://mavlink.io/en/services/gimbal_v2.html
+//
+// Frame is STX, payload length, incompatibility and compatibility flags, sequence, system and component IDs,
+// message ID (3 bytes), payload with trailing zero bytes cut off, and CRC (X.25) of all that but STX, plus CRC_EXTRA of the message.
+// Payload fields go in order of their size, largest first, all little endian.
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+const MAVLINK_MAX_FRAME_BYTES = 12 + 35 // header and CRC, largest payload of messages here
+
+const (
+	mavlinkSTX          = 0xFD
+	mavlinkHeaderBytes  = 10
+	mavlinkVersion      = 3
+	mavlinkTypeGeneric  = 0 // MAV_TYPE_GENERIC
+	mavlinkAutopilotNA  = 8 // MAV_AUTOPILOT_INVALID, not an autopilot
+	mavlinkStateActive  = 4 // MAV_STATE_ACTIVE
+	mavlinkMaxPayload   = MAVLINK_MAX_FRAME_BYTES - mavlinkHeaderBytes - 2
+	mavlinkCrcInitValue = 0xFFFF
+)
+
+// Message IDs and their CRC_EXTRA, seeds of checksum that tell message definition
+const (
+	mavlinkHeartbeat                = 0
+	mavlinkHeartbeatExtra           = 50
+	mavlinkGimbalManagerSetAttitude = 282
+	mavlinkGimbalManagerExtra       = 123
+	mavlinkGimbalDeviceSetAttitude  = 284
+	mavlinkGimbalDeviceExtra        = 99
+)
+
+// Gimbal flags, same bits for GIMBAL_MANAGER_FLAGS and GIMBAL_DEVICE_FLAGS
+const (
+	MavlinkGimbalRollLock  = 4  // roll relative to horizon, not vehicle
+	MavlinkGimbalPitchLock = 8  // pitch relative to horizon, not vehicle
+	MavlinkGimbalYawLock   = 16 // yaw relative to north, not vehicle
+)
+
+type Mavlink struct {
+	System    byte
+	Component byte
+	sequence  byte
+	payload   [mavlinkMaxPayload]byte
+}
+
+// HEARTBEAT, once a second tells the other end this component is there, returns frame length
+func (m *Mavlink) Heartbeat(frame []byte) int {
+	p := m.payload[:9]
+	binary.LittleEndian.PutUint32(p[0:], 0) // custom mode
+	p[4] = mavlinkTypeGeneric
+	p[5] = mavlinkAutopilotNA
+	p[6] = 0 // base mode
+	p[7] = mavlinkStateActive
+	p[8] = mavlinkVersion
+	return m.pack(frame, mavlinkHeartbeat, mavlinkHeartbeatExtra, p)
+}
+
+// GIMBAL_MANAGER_SET_ATTITUDE, attitude (w, x, y, z, forward-right-down frame) for gimbal manager, usually the autopilot, returns frame length.
+// Gimbal device 0 means all gimbals, angular velocities are not used.
+func (m *Mavlink) GimbalManagerSetAttitude(frame []byte, targetSystem, targetComponent byte, flags uint32, q [4]float32) int {
+	p := m.payload[:35]
+	binary.LittleEndian.PutUint32(p[0:], flags)
+	putQuaternionAndRates(p[4:], q)
+	p[32] = targetSystem
+	p[33] = targetComponent
+	p[34] = 0 // gimbal device id
+	return m.pack(frame, mavlinkGimbalManagerSetAttitude, mavlinkGimbalManagerExtra, p)
+}
+
+// GIMBAL_DEVICE_SET_ATTITUDE, attitude (w, x, y, z, forward-right-down frame) for gimbal device directly, returns frame length
+func (m *Mavlink) GimbalDeviceSetAttitude(frame []byte, targetSystem, targetComponent byte, flags uint16, q [4]float32) int {
+	p := m.payload[:32]
+	putQuaternionAndRates(p[0:], q)
+	binary.LittleEndian.PutUint16(p[28:], flags)
+	p[30] = targetSystem
+	p[31] = targetComponent
+	return m.pack(frame, mavlinkGimbalDeviceSetAttitude, mavlinkGimbalDeviceExtra, p)
+}
+
+// Quaternion and angular velocities (NaN, not used), 28 bytes
+func putQuaternionAndRates(p []byte, q [4]float32) {
+	for i, v := range q {
+		binary.LittleEndian.PutUint32(p[i*4:], math.Float32bits(v))
+	}
+	for i := 0; i < 3; i++ {
+		binary.LittleEndian.PutUint32(p[16+i*4:], math.Float32bits(float32(math.NaN())))
+	}
+}
+
+func (m *Mavlink) pack(frame []byte, id uint32, extra byte, payload []byte) int {
+	// trailing zeros are not sent, but at least one byte is
+	n := len(payload)
+	for n > 1 && payload[n-1] == 0 {
+		n--
+	}
+	frame[0] = mavlinkSTX
+	frame[1] = byte(n)
+	frame[2] = 0 // incompatibility flags, no signing
+	frame[3] = 0 // compatibility flags
+	frame[4] = m.sequence
+	frame[5] = m.System
+	frame[6] = m.Component
+	frame[7] = byte(id)
+	frame[8] = byte(id >> 8)
+	frame[9] = byte(id >> 16)
+	copy(frame[mavlinkHeaderBytes:], payload[:n])
+	crc := MavlinkCrc(mavlinkCrcInitValue, frame[1:mavlinkHeaderBytes+n])
+	crc = mavlinkCrcByte(crc, extra)
+	binary.LittleEndian.PutUint16(frame[mavlinkHeaderBytes+n:], crc)
+	m.sequence++
+	return mavlinkHeaderBytes + n + 2
+}
+
+// CRC-16/MCRF4XX (X.25 as MAVLink calls it), accumulated over data, start with 0xFFFF
+func MavlinkCrc(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		crc = mavlinkCrcByte(crc, b)
+	}
+	return crc
+}
+
+func mavlinkCrcByte(crc uint16, b byte) uint16 {
+	t := b ^ byte(crc)
+	t ^= t << 4
+	return crc>>8 ^ uint16(t)<<8 ^ uint16(t)<<3 ^ uint16(t)>>4
+}
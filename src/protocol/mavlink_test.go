@@ -0,0 +1,129 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// Message field, as in message definition, in wire order
+type mavlinkField struct {
+	typ   string
+	name  string
+	array byte
+}
+
+// CRC_EXTRA worked out from message definition, as MAVLink generators do
+func mavlinkExtra(message string, fields []mavlinkField) byte {
+	crc := MavlinkCrc(0xFFFF, []byte(message+" "))
+	for _, f := range fields {
+		crc = MavlinkCrc(crc, []byte(f.typ+" "+f.name+" "))
+		if f.array > 0 {
+			crc = MavlinkCrc(crc, []byte{f.array})
+		}
+	}
+	return byte(crc) ^ byte(crc>>8)
+}
+
+func TestMavlinkCrc(t *testing.T) {
+	if got := MavlinkCrc(0xFFFF, []byte("123456789")); got != 0x6F91 { // check value
+		t.Errorf("check value %#04X, want 0x6F91", got)
+	}
+}
+
+func TestMavlinkCrcExtra(t *testing.T) {
+	cases := []struct {
+		message string
+		fields  []mavlinkField
+		want    byte
+	}{
+		{"HEARTBEAT", []mavlinkField{
+			{"uint32_t", "custom_mode", 0}, {"uint8_t", "type", 0}, {"uint8_t", "autopilot", 0},
+			{"uint8_t", "base_mode", 0}, {"uint8_t", "system_status", 0}, {"uint8_t", "mavlink_version", 0},
+		}, mavlinkHeartbeatExtra},
+		{"GIMBAL_MANAGER_SET_ATTITUDE", []mavlinkField{
+			{"uint32_t", "flags", 0}, {"float", "q", 4},
+			{"float", "angular_velocity_x", 0}, {"float", "angular_velocity_y", 0}, {"float", "angular_velocity_z", 0},
+			{"uint8_t", "target_system", 0}, {"uint8_t", "target_component", 0}, {"uint8_t", "gimbal_device_id", 0},
+		}, mavlinkGimbalManagerExtra},
+		{"GIMBAL_DEVICE_SET_ATTITUDE", []mavlinkField{
+			{"float", "q", 4},
+			{"float", "angular_velocity_x", 0}, {"float", "angular_velocity_y", 0}, {"float", "angular_velocity_z", 0},
+			{"uint16_t", "flags", 0}, {"uint8_t", "target_system", 0}, {"uint8_t", "target_component", 0},
+		}, mavlinkGimbalDeviceExtra},
+	}
+	for _, c := range cases {
+		if got := mavlinkExtra(c.message, c.fields); got != c.want {
+			t.Errorf("%s: CRC_EXTRA is %d, message definition gives %d", c.message, c.want, got)
+		}
+	}
+}
+
+func TestMavlinkFrames(t *testing.T) {
+	flags := uint32(MavlinkGimbalRollLock | MavlinkGimbalPitchLock)
+	cases := []struct {
+		name   string
+		encode func(m *Mavlink, frame []byte) int
+		want   string
+	}{
+		{
+			"heartbeat, no trailing zeros",
+			func(m *Mavlink, frame []byte) int { return m.Heartbeat(frame) },
+			"FD 09 00 00 00 FE 19 00 00 00 00 00 00 00 00 08 00 04 03 0B 4B",
+		},
+		{
+			"gimbal manager, level, gimbal device id (zero) is cut off",
+			func(m *Mavlink, frame []byte) int {
+				return m.GimbalManagerSetAttitude(frame, 1, 1, flags, [4]float32{1, 0, 0, 0})
+			},
+			"FD 22 00 00 01 FE 19 1A 01 00 0C 00 00 00 00 00 80 3F 00 00 00 00 00 00 00 00 00 00 00 00 00 00 C0 7F 00 00 C0 7F 00 00 C0 7F 01 01 A2 D4",
+		},
+		{
+			"gimbal device, turned",
+			func(m *Mavlink, frame []byte) int {
+				return m.GimbalDeviceSetAttitude(frame, 1, 154, uint16(flags), [4]float32{0.5, 0.5, -0.5, 0.5})
+			},
+			"FD 20 00 00 02 FE 19 1C 01 00 00 00 00 3F 00 00 00 3F 00 00 00 BF 00 00 00 3F 00 00 C0 7F 00 00 C0 7F 00 00 C0 7F 0C 00 01 9A 57 3C",
+		},
+	}
+	// one link for all, sequence goes up frame by frame
+	link := Mavlink{System: 254, Component: 25}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			frame := make([]byte, MAVLINK_MAX_FRAME_BYTES)
+			n := c.encode(&link, frame)
+			checkFrame(t, frame[:n], fromHex(t, c.want))
+		})
+	}
+}
+
+// Payload packing: trailing zeros are cut off, but at least one byte goes, checksum covers what is sent
+func TestMavlinkPack(t *testing.T) {
+	cases := []struct {
+		name    string
+		payload []byte
+		sent    int
+	}{
+		{"all zeros", []byte{0, 0, 0, 0}, 1},
+		{"trailing zeros", []byte{1, 0, 2, 0, 0}, 3},
+		{"no zeros", []byte{1, 2, 3}, 3},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			link := Mavlink{System: 1, Component: 2, sequence: 7}
+			frame := make([]byte, MAVLINK_MAX_FRAME_BYTES)
+			n := link.pack(frame, 0x030201, 42, c.payload)
+			checkFrame(t, frame[:mavlinkHeaderBytes], []byte{0xFD, byte(c.sent), 0, 0, 7, 1, 2, 0x01, 0x02, 0x03})
+			if n != mavlinkHeaderBytes+c.sent+2 {
+				t.Fatalf("frame length %d, want %d", n, mavlinkHeaderBytes+c.sent+2)
+			}
+			checkFrame(t, frame[mavlinkHeaderBytes:n-2], c.payload[:c.sent])
+			want := mavlinkCrcByte(MavlinkCrc(0xFFFF, frame[1:n-2]), 42)
+			if got := binary.LittleEndian.Uint16(frame[n-2:]); got != want {
+				t.Errorf("checksum %#04X, want %#04X", got, want)
+			}
+			if link.sequence != 8 {
+				t.Errorf("sequence %d, want 8", link.sequence)
+			}
+		})
+	}
+}
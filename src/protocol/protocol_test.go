@@ -0,0 +1,35 @@
+package protocol
+
+// Golden frames in these tests are worked out independently from protocol specifications, not by the encoders under test.
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Bytes from hex, spaces between them are fine
+func fromHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Frame from hex head, zero bytes and hex tail
+func golden(t *testing.T, head string, zeros int, tail string) []byte {
+	t.Helper()
+	result := fromHex(t, head)
+	result = append(result, make([]byte, zeros)...)
+	return append(result, fromHex(t, tail)...)
+}
+
+func checkFrame(t *testing.T, got, want []byte) {
+	t.Helper()
+	if !bytes.Equal(got, want) {
+		t.Errorf("\n got  % X\n want % X", got, want)
+	}
+}
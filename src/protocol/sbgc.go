@@ -0,0 +1,47 @@
+package protocol
+
+// SimpleBGC (BaseCam) brushless gimbal controller, serial API v1
+// https://www.basecamelectronics.com/serialapi/
+//
+// Frame is start sign, command, payload size, header checksum (command plus size),
+// payload and payload checksum (sum of its bytes).
+// CMD_CONTROL payload is mode and then speed and angle (int16 each) for roll, pitch and yaw.
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+const SBGC_CONTROL_FRAME_BYTES = 18
+
+const (
+	sbgcStart            = '>'
+	sbgcCmdControl       = 67
+	sbgcControlSize      = 13
+	sbgcControlModeAngle = 2
+	sbgcSpeedUnit        = 0.1220740379    // dps
+	sbgcAngleUnit        = 360.0 / 16384.0 // degrees
+)
+
+// Encode CMD_CONTROL frame in angle mode, angles in degrees, speed is the largest to reach them (dps)
+func SbgcControlAngles(frame *[SBGC_CONTROL_FRAME_BYTES]byte, roll, pitch, yaw, speed float64) {
+	frame[0] = sbgcStart
+	frame[1] = sbgcCmdControl
+	frame[2] = sbgcControlSize
+	frame[3] = sbgcCmdControl + sbgcControlSize
+	frame[4] = sbgcControlModeAngle
+	s := sbgcValue(speed, sbgcSpeedUnit)
+	for i, a := range [3]float64{roll, pitch, yaw} {
+		binary.LittleEndian.PutUint16(frame[5+i*4:], s)
+		binary.LittleEndian.PutUint16(frame[7+i*4:], sbgcValue(a, sbgcAngleUnit))
+	}
+	sum := byte(0)
+	for _, b := range frame[4:17] {
+		sum += b
+	}
+	frame[17] = sum
+}
+
+func sbgcValue(v, unit float64) uint16 {
+	return uint16(int16(math.Max(math.Min(math.Round(v/unit), math.MaxInt16), math.MinInt16)))
+}
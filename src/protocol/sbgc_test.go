@@ -0,0 +1,45 @@
+package protocol
+
+import "testing"
+
+func TestSbgcControlAngles(t *testing.T) {
+	cases := []struct {
+		name                    string
+		roll, pitch, yaw, speed float64
+		want                    string
+	}{
+		{"level", 0, 0, 0, 300, "3E 43 0D 50 02 9A 09 00 00 9A 09 00 00 9A 09 00 00 EB"},
+		{"turned", -5, 10, 90, 300, "3E 43 0D 50 02 9A 09 1C FF 9A 09 C7 01 9A 09 00 10 DE"},
+		{"out of range, clamped", 0, 0, 1000, 5000, "3E 43 0D 50 02 FF 7F 00 00 FF 7F 00 00 FF 7F FF 7F FA"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var frame [SBGC_CONTROL_FRAME_BYTES]byte
+			SbgcControlAngles(&frame, c.roll, c.pitch, c.yaw, c.speed)
+			checkFrame(t, frame[:], fromHex(t, c.want))
+		})
+	}
+}
+
+// Header checksum is command plus payload size, body checksum is sum of payload bytes, both modulo 256
+func TestSbgcChecksums(t *testing.T) {
+	cases := []struct {
+		roll, pitch, yaw, speed float64
+		body                    byte
+	}{
+		{0, 0, 0, 0, 0x02},
+		{0, 0, 0, 300, 0xEB},
+		{-5, 10, 90, 300, 0xDE},
+		{-180, 45, -90, 1000, 0x3A},
+	}
+	for _, c := range cases {
+		var frame [SBGC_CONTROL_FRAME_BYTES]byte
+		SbgcControlAngles(&frame, c.roll, c.pitch, c.yaw, c.speed)
+		if frame[3] != 0x50 {
+			t.Errorf("header checksum %#02X, want 0x50", frame[3])
+		}
+		if frame[17] != c.body {
+			t.Errorf("%v: body checksum %#02X, want %#02X", c, frame[17], c.body)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package protocol
+
+// Frames of wired links, no hardware dependencies, so they build and are tested on host.
+//
+// SBUS, Futaba serial bus, 100000 baud 8E2, inverted line
+// https://github.com/bolderflight/sbus/blob/main/README.md
+//
+// Frame is 25 bytes: header, 16 channels of 11 bits packed little endian, flags, footer.
+
+const (
+	SBUS_CHANNELS    = 16
+	SBUS_FRAME_BYTES = 25
+	SBUS_BAUD_RATE   = 100_000
+	SBUS_LINE_BITS   = SBUS_FRAME_BYTES * 12 // start, 8 data, parity and 2 stop bits per byte
+)
+
+const (
+	sbusHeader = 0x0F
+	sbusFooter = 0x00
+)
+
+// Flags byte, bits 0 and 1 are digital channels 17 and 18, not used
+const (
+	SbusFlagFrameLost = 0x04
+	SbusFlagFailsafe  = 0x08
+)
+
+// Channel value for pulse width (us), 988..2012us maps onto 172..1811, 1500us is 992, CRSF uses the same scale
+func SbusValue(us uint16) uint16 {
+	v := (int32(us) - 880) * 8 / 5
+	return uint16(min(max(v, 0), 0x7FF))
+}
+
+// Channels (us) packed 11 bits each, little endian, into 22 bytes, as both SBUS and CRSF do
+func packChannels(dst []byte, channels *[SBUS_CHANNELS]uint16) {
+	acc, bits, idx := uint32(0), 0, 0
+	for _, us := range channels {
+		acc |= uint32(SbusValue(us)) << bits
+		bits += 11
+		for bits >= 8 {
+			dst[idx] = byte(acc)
+			idx++
+			acc >>= 8
+			bits -= 8
+		}
+	}
+}
+
+// Encode channels (us) and flags into frame
+func SbusEncode(frame *[SBUS_FRAME_BYTES]byte, channels *[SBUS_CHANNELS]uint16, flags byte) {
+	frame[0] = sbusHeader
+	packChannels(frame[1:23], channels)
+	frame[23] = flags
+	frame[24] = sbusFooter
+}
+
+// Line levels of frame, one per bit, for links that drive the line bit by bit.
+// Line is inverted: idle and stop bits are low, start bit is high, data bits go least significant first, high for 0.
+func SbusLine(line *[SBUS_LINE_BITS]uint16, frame *[SBUS_FRAME_BYTES]byte, high, low uint16) {
+	i := 0
+	for _, b := range frame {
+		line[i] = high // start
+		parity := byte(0)
+		for k := 1; k <= 8; k++ {
+			bit := b & 1
+			parity ^= bit
+			line[i+k] = high
+			if bit == 1 {
+				line[i+k] = low
+			}
+			b >>= 1
+		}
+		line[i+9] = high // even parity
+		if parity == 1 {
+			line[i+9] = low
+		}
+		line[i+10] = low // stop
+		line[i+11] = low // stop
+		i += 12
+	}
+}
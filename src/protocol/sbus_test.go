@@ -0,0 +1,111 @@
+package protocol
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSbusEncode(t *testing.T) {
+	cases := []struct {
+		name     string
+		channels map[int]uint16 // the rest are zero
+		flags    byte
+		head     string
+		zeros    int
+		tail     string
+	}{
+		{"zero", nil, 0, "0F", 22, "00 00"},
+		{"first channel full, 11 bits from the start", map[int]uint16{0: 2500}, 0, "0F FF 07", 20, "00 00"},
+		{"second channel full, 11 bits from bit 11", map[int]uint16{1: 2500}, 0, "0F 00 F8 3F", 19, "00 00"},
+		{"last channel full, 11 bits to the end", map[int]uint16{15: 2500}, 0, "0F", 20, "E0 FF 00 00"},
+		{"flags", nil, SbusFlagFrameLost | SbusFlagFailsafe, "0F", 22, "0C 00"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var frame [SBUS_FRAME_BYTES]byte
+			var channels [SBUS_CHANNELS]uint16
+			for i, v := range c.channels {
+				channels[i] = v
+			}
+			SbusEncode(&frame, &channels, c.flags)
+			checkFrame(t, frame[:], golden(t, c.head, c.zeros, c.tail))
+		})
+	}
+}
+
+func TestSbusValue(t *testing.T) {
+	cases := []struct {
+		us   uint16
+		want uint16
+	}{
+		{0, 0},
+		{880, 0},
+		{988, 172},
+		{1500, 992},
+		{2012, 1811},
+		{2160, 2047},
+		{3000, 2047},
+	}
+	for _, c := range cases {
+		if got := SbusValue(c.us); got != c.want {
+			t.Errorf("SbusValue(%d) = %d, want %d", c.us, got, c.want)
+		}
+	}
+}
+
+// Every channel comes back, decoded as receivers do
+func TestSbusDecode(t *testing.T) {
+	var frame [SBUS_FRAME_BYTES]byte
+	var channels [SBUS_CHANNELS]uint16
+	rnd := rand.New(rand.NewSource(1))
+	for i := range channels {
+		channels[i] = uint16(988 + rnd.Intn(1024))
+	}
+	SbusEncode(&frame, &channels, 0)
+	for i, us := range channels {
+		bit := 8 + i*11
+		v := (uint32(frame[bit/8]) | uint32(frame[bit/8+1])<<8 | uint32(frame[bit/8+2])<<16) >> (bit % 8) & 0x7FF
+		if want := SbusValue(us); uint16(v) != want {
+			t.Errorf("channel %d decodes to %d, want %d", i, v, want)
+		}
+	}
+}
+
+func TestSbusLine(t *testing.T) {
+	const H, L = 1, 0
+	var frame [SBUS_FRAME_BYTES]byte
+	var channels [SBUS_CHANNELS]uint16
+	var line [SBUS_LINE_BITS]uint16
+	channels[0] = 2500 // first data bytes 0xFF and 0x07
+	SbusEncode(&frame, &channels, SbusFlagFrameLost)
+	SbusLine(&line, &frame, H, L)
+
+	cases := []struct {
+		name string
+		byte int
+		want [12]uint16 // start, data bits, least significant first, parity, stop, stop
+	}{
+		{"header 0x0F, even ones", 0, [12]uint16{H, L, L, L, L, H, H, H, H, H, L, L}},
+		{"0xFF, even ones", 1, [12]uint16{H, L, L, L, L, L, L, L, L, H, L, L}},
+		{"0x07, odd ones", 2, [12]uint16{H, L, L, L, H, H, H, H, H, L, L, L}},
+		{"zero", 3, [12]uint16{H, H, H, H, H, H, H, H, H, H, L, L}},
+		{"flags 0x04, odd ones", 23, [12]uint16{H, H, H, L, H, H, H, H, H, L, L, L}},
+	}
+	for _, c := range cases {
+		if got := [12]uint16(line[c.byte*12:]); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	// every byte comes back, sampled as an inverting receiver does
+	for i := range frame {
+		bits := line[i*12 : i*12+12]
+		b := byte(0)
+		for k := 8; k >= 1; k-- {
+			b = b<<1 | byte(bits[k]^1)
+		}
+		if b != frame[i] {
+			t.Errorf("byte %d comes back as %#02X, want %#02X", i, b, frame[i])
+		}
+	}
+}
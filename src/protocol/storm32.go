@@ -0,0 +1,33 @@
+package protocol
+
+// STorM32 brushless gimbal controller, serial RC commands
+// https://www.olliw.eu/storm32bgc-wiki/Serial_Communication
+//
+// Frame is start sign, payload length, command, payload and CRC (X.25, same as MAVLink) of all that but start sign.
+// CMD_SETANGLES payload is pitch, roll and yaw (float, degrees), flags (0, no limits) and type (0).
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+const STORM32_SETANGLES_FRAME_BYTES = 19
+
+const (
+	storm32Start         = 0xFA
+	storm32CmdSetAngles  = 17
+	storm32SetAnglesSize = 14
+)
+
+// Encode CMD_SETANGLES frame, angles in degrees
+func Storm32SetAngles(frame *[STORM32_SETANGLES_FRAME_BYTES]byte, pitch, roll, yaw float32) {
+	frame[0] = storm32Start
+	frame[1] = storm32SetAnglesSize
+	frame[2] = storm32CmdSetAngles
+	binary.LittleEndian.PutUint32(frame[3:], math.Float32bits(pitch))
+	binary.LittleEndian.PutUint32(frame[7:], math.Float32bits(roll))
+	binary.LittleEndian.PutUint32(frame[11:], math.Float32bits(yaw))
+	frame[15] = 0 // flags
+	frame[16] = 0 // type
+	binary.LittleEndian.PutUint16(frame[17:], MavlinkCrc(0xFFFF, frame[1:17]))
+}
@@ -0,0 +1,38 @@
+package protocol
+
+import "testing"
+
+func TestStorm32SetAngles(t *testing.T) {
+	cases := []struct {
+		name             string
+		pitch, roll, yaw float32
+		want             string
+	}{
+		{"level", 0, 0, 0, "FA 0E 11 00 00 00 00 00 00 00 00 00 00 00 00 00 00 0F D6"},
+		{"turned", 10, -5, 90, "FA 0E 11 00 00 20 41 00 00 A0 C0 00 00 B4 42 00 00 9C F9"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var frame [STORM32_SETANGLES_FRAME_BYTES]byte
+			Storm32SetAngles(&frame, c.pitch, c.roll, c.yaw)
+			checkFrame(t, frame[:], fromHex(t, c.want))
+		})
+	}
+}
+
+// X.25 CRC of STorM32 frames, low byte first, covers length, command and payload
+func TestStorm32Crc(t *testing.T) {
+	cases := []struct {
+		data string
+		want uint16
+	}{
+		{"31 32 33 34 35 36 37 38 39", 0x6F91}, // check value
+		{"0E 11 00 00 00 00 00 00 00 00 00 00 00 00 00 00", 0xD60F},
+		{"0E 11 00 00 20 41 00 00 A0 C0 00 00 B4 42 00 00", 0xF99C},
+	}
+	for _, c := range cases {
+		if got := MavlinkCrc(0xFFFF, fromHex(t, c.data)); got != c.want {
+			t.Errorf("CRC of %s is %#04X, want %#04X", c.data, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"machine"
+
+	"github.com/ysoldak/HeadTracker/src/trainer"
+)
+
+// Serial console only command, toggles motion recording, see "printMotion"
+const serialMotionRecord = 'M'
+
+// Serial console accepts some of one-character Bluetooth commands, see trainer.CMD_*
+// Handy in PPM mode, when there is no Bluetooth.
+func handleSerial() {
+	for machine.Serial.Buffered() > 0 {
+		c, err := machine.Serial.ReadByte()
+		if err != nil {
+			return
+		}
+		switch c {
+		case trainer.CMD_ORIENTATION_RESET:
+			println("Orientation reset via serial command")
+			resetOrientation(state.recenterModes[recenterSourceCommand])
+		case trainer.CMD_ACC_CALIBRATE:
+			println("Accelerometer calibration via serial command")
+			startAccCalibration()
+		case trainer.CMD_HOLD:
+			println("Output hold via serial command")
+			toggleHold()
+		case serialMotionRecord:
+			state.recording = !state.recording
+		case trainer.CMD_REBOOT:
+			println("Reboot via serial command")
+			machine.CPUReset()
+		}
+	}
+}
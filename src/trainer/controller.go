@@ -0,0 +1,118 @@
+package trainer
+
+// Brushless gimbal controllers on UART TX pin, STorM32 and SimpleBGC, drive a 3-axis gimbal with no radio in between
+//
+// Angle commands go every 20ms, from orientation angles (see "SetAngles"), channels do not apply.
+// Angles are in degrees: pitch up, roll right and yaw right are positive, any of them can be inverted.
+// Commands stop while IMU fails to read.
+//
+// Configuration has 3 bytes:
+// - baud rate    9600 units, e.g. 12 is 115200,
+// - inverted     bit 0 pitch, bit 1 roll, bit 2 yaw,
+// - speed        10 dps units, the largest speed to reach commanded angles, SimpleBGC only.
+
+import (
+	"machine"
+	"math"
+	"time"
+
+	"github.com/ysoldak/HeadTracker/src/protocol"
+)
+
+const CONTROLLER_BYTES = 3
+
+const controllerFramePeriod = 20 * time.Millisecond
+
+type controller struct {
+	uart     *machine.UART
+	baudRate uint32
+	inverted byte
+	speed    float64 // dps
+
+	angles   [3]float64 // degrees, pitch, roll, yaw
+	failsafe bool
+}
+
+func newController(uart *machine.UART, config [CONTROLLER_BYTES]byte) controller {
+	return controller{
+		uart:     uart,
+		baudRate: uint32(max(config[0], 1)) * 9600,
+		inverted: config[1],
+		speed:    float64(config[2]) * 10,
+	}
+}
+
+// Configure UART and send a frame every period, unless failsafe
+func (c *controller) start(send func()) {
+	c.uart.Configure(machine.UARTConfig{
+		BaudRate: c.baudRate,
+		TX:       machine.UART_TX_PIN,
+		RX:       machine.UART_RX_PIN,
+	})
+	go func() {
+		ticker := time.NewTicker(controllerFramePeriod)
+		for range ticker.C {
+			if !c.failsafe {
+				send()
+			}
+		}
+	}()
+}
+
+// Channels do not apply, angles go instead
+func (c *controller) SetChannel(n int, v uint16) {
+}
+
+// Angles (radians) around head axes: X to the right (tilt), Y forward (roll), Z up (pan)
+func (c *controller) SetAngles(angles [3]float64) {
+	c.angles = [3]float64{angles[0], angles[1], -angles[2]} // pan to the left is positive around Z up
+	for i := range c.angles {
+		c.angles[i] *= 180 / math.Pi
+		if c.inverted&(1<<i) != 0 {
+			c.angles[i] = -c.angles[i]
+		}
+	}
+}
+
+// Commands stop on failsafe, lost IMU data alone does not matter, angles hold meanwhile
+func (c *controller) SetHealth(lost, failsafe bool) {
+	c.failsafe = failsafe
+}
+
+// --- STorM32 ----------------------------------------------------------------
+
+type STorM32 struct {
+	controller
+	frame [protocol.STORM32_SETANGLES_FRAME_BYTES]byte
+}
+
+func NewSTorM32(uart *machine.UART, config [CONTROLLER_BYTES]byte) *STorM32 {
+	return &STorM32{controller: newController(uart, config)}
+}
+
+func (s *STorM32) Start() string {
+	s.start(func() {
+		protocol.Storm32SetAngles(&s.frame, float32(s.angles[0]), float32(s.angles[1]), float32(s.angles[2]))
+		s.uart.Write(s.frame[:])
+	})
+	return "STORM32 OUTPUT"
+}
+
+// --- SimpleBGC --------------------------------------------------------------
+
+type SimpleBGC struct {
+	controller
+	frame [protocol.SBGC_CONTROL_FRAME_BYTES]byte
+}
+
+func NewSimpleBGC(uart *machine.UART, config [CONTROLLER_BYTES]byte) *SimpleBGC {
+	return &SimpleBGC{controller: newController(uart, config)}
+}
+
+func (s *SimpleBGC) Start() string {
+	s.start(func() {
+		protocol.SbgcControlAngles(&s.frame, s.angles[1], s.angles[0], s.angles[2], s.speed)
+		s.uart.Write(s.frame[:])
+	})
+	return "   SBGC OUTPUT"
+}
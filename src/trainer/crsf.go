@@ -0,0 +1,65 @@
+package trainer
+
+// CRSF wired trainer link, RC_CHANNELS_PACKED frames on UART TX pin, see "protocol.CrsfEncodeChannels"
+//
+// Feeds ExpressLRS backpacks and CRSF capable receivers and flight controllers directly.
+// CRSF has no failsafe flag, frames stop instead while IMU fails to read, so receiving end goes to its own failsafe.
+
+import (
+	"machine"
+	"time"
+
+	"github.com/ysoldak/HeadTracker/src/protocol"
+)
+
+const crsfFramePeriod = 20 * time.Millisecond
+
+type CRSF struct {
+	uart     *machine.UART
+	channels [protocol.CRSF_CHANNELS]uint16
+	failsafe bool
+	frame    [protocol.CRSF_RC_FRAME_BYTES]byte
+}
+
+func NewCRSF(uart *machine.UART) *CRSF {
+	c := &CRSF{
+		uart: uart,
+	}
+	for i := range c.channels {
+		c.channels[i] = 1500
+	}
+	return c
+}
+
+func (c *CRSF) Start() string {
+	c.uart.Configure(machine.UARTConfig{
+		BaudRate: protocol.CRSF_BAUD_RATE,
+		TX:       machine.UART_TX_PIN,
+		RX:       machine.UART_RX_PIN,
+	})
+
+	go func() {
+		ticker := time.NewTicker(crsfFramePeriod)
+		for range ticker.C {
+			if c.failsafe {
+				continue
+			}
+			protocol.CrsfEncodeChannels(&c.frame, &c.channels)
+			c.uart.Write(c.frame[:])
+		}
+	}()
+
+	return "   CRSF OUTPUT"
+}
+
+func (c *CRSF) SetChannel(n int, v uint16) {
+	if n >= len(c.channels) {
+		return
+	}
+	c.channels[n] = v
+}
+
+// Frames stop on failsafe, lost IMU data alone does not matter, channels hold meanwhile
+func (c *CRSF) SetHealth(lost, failsafe bool) {
+	c.failsafe = failsafe
+}
@@ -0,0 +1,106 @@
+package trainer
+
+// MAVLink v2 wired link on UART TX pin, gimbal attitude for ArduPilot and PX4, see "protocol.Mavlink"
+//
+// Attitude comes as quaternion straight from orientation (see "SetAttitude"), channels do not apply.
+// Roll and pitch are relative to horizon, yaw follows the vehicle.
+// Heartbeat goes once a second, attitude messages stop while IMU fails to read.
+//
+// Configuration has 6 bytes:
+// - system       system ID of the head tracker,
+// - component    component ID of the head tracker,
+// - target       system ID of the vehicle,
+// - target       component ID, of autopilot (gimbal manager) or of gimbal device,
+// - message      0 GIMBAL_MANAGER_SET_ATTITUDE, 1 GIMBAL_DEVICE_SET_ATTITUDE,
+// - baud rate    9600 units, e.g. 6 is 57600, 12 is 115200.
+
+import (
+	"machine"
+	"time"
+
+	"github.com/ysoldak/HeadTracker/src/protocol"
+)
+
+const MAVLINK_BYTES = 6
+
+const (
+	mavlinkMessageManager = 0
+	mavlinkMessageDevice  = 1
+)
+
+const (
+	mavlinkFramePeriod     = 20 * time.Millisecond
+	mavlinkHeartbeatPeriod = time.Second
+	mavlinkGimbalFlags     = protocol.MavlinkGimbalRollLock | protocol.MavlinkGimbalPitchLock
+)
+
+type MAVLink struct {
+	uart            *machine.UART
+	baudRate        uint32
+	link            protocol.Mavlink
+	targetSystem    byte
+	targetComponent byte
+	message         byte
+
+	attitude [4]float32 // w, x, y, z, forward-right-down frame
+	failsafe bool
+	frame    [protocol.MAVLINK_MAX_FRAME_BYTES]byte
+}
+
+func NewMAVLink(uart *machine.UART, config [MAVLINK_BYTES]byte) *MAVLink {
+	return &MAVLink{
+		uart:            uart,
+		baudRate:        uint32(max(config[5], 1)) * 9600,
+		link:            protocol.Mavlink{System: config[0], Component: config[1]},
+		targetSystem:    config[2],
+		targetComponent: config[3],
+		message:         config[4],
+		attitude:        [4]float32{1, 0, 0, 0},
+	}
+}
+
+func (m *MAVLink) Start() string {
+	m.uart.Configure(machine.UARTConfig{
+		BaudRate: m.baudRate,
+		TX:       machine.UART_TX_PIN,
+		RX:       machine.UART_RX_PIN,
+	})
+
+	go func() {
+		ticker := time.NewTicker(mavlinkFramePeriod)
+		heartbeat := time.Time{}
+		for range ticker.C {
+			if time.Since(heartbeat) >= mavlinkHeartbeatPeriod {
+				heartbeat = time.Now()
+				n := m.link.Heartbeat(m.frame[:])
+				m.uart.Write(m.frame[:n])
+			}
+			if m.failsafe {
+				continue
+			}
+			n := 0
+			if m.message == mavlinkMessageDevice {
+				n = m.link.GimbalDeviceSetAttitude(m.frame[:], m.targetSystem, m.targetComponent, mavlinkGimbalFlags, m.attitude)
+			} else {
+				n = m.link.GimbalManagerSetAttitude(m.frame[:], m.targetSystem, m.targetComponent, mavlinkGimbalFlags, m.attitude)
+			}
+			m.uart.Write(m.frame[:n])
+		}
+	}()
+
+	return "MAVLINK OUTPUT"
+}
+
+// Channels do not apply, attitude goes instead
+func (m *MAVLink) SetChannel(n int, v uint16) {
+}
+
+// Attitude (w, x, y, z) in head frame, X to the right, Y forward, Z up
+func (m *MAVLink) SetAttitude(q [4]float64) {
+	m.attitude = [4]float32{float32(q[0]), float32(q[2]), float32(q[1]), float32(-q[3])} // to forward-right-down frame
+}
+
+// Attitude messages stop on failsafe, lost IMU data alone does not matter, attitude holds meanwhile
+func (m *MAVLink) SetHealth(lost, failsafe bool) {
+	m.failsafe = failsafe
+}
@@ -15,6 +15,8 @@ const (
 	CMD_ORIENTATION_RESET = 'R'    // reset orientation
 	CMD_FACTORY_RESET     = 'F'    // factory reset
 	CMD_REBOOT            = 'B'    // reboot device
+	CMD_ACC_CALIBRATE     = 'A'    // start six-position accelerometer calibration
+	CMD_HOLD              = 'H'    // toggle output hold
 )
 
 // 'D' is for Data (to persist)
@@ -24,20 +26,245 @@ const (
 
 	// axis mapping (3 bytes) - one byte per axis
 	//
-	// each byte has format: 0b00IE0OOO where
-	// - '0'   bit is not used,
-	// - 'I'   bit for inverted(1)/not inverted(0),
-	// - 'E'   bit for enabled(1)/disabled(0)
-	// - 'OOO' three bits for channel index offset (0-7),
+	// each byte has format: 0b00IEOOOO where
+	// - '0'    bit is not used,
+	// - 'I'    bit for inverted(1)/not inverted(0),
+	// - 'E'    bit for enabled(1)/disabled(0)
+	// - 'OOOO' four bits for channel index offset (0-15), Bluetooth carries channels 1-8 only, wired outputs up to 16,
 	//
 	// examples:
 	// - 0x10 means axis mapped to channel 1 (offset 0), enabled,  not inverted
 	// - 0x11 means axis mapped to channel 2 (offset 1), enabled,  not inverted
 	// - 0x25 means axis mapped to channel 6 (offset 5), disabled, inverted
 	// - 0x34 means axis mapped to channel 5 (offset 4), enabled,  inverted
+	// - 0x1B means axis mapped to channel 12 (offset 11), enabled, not inverted
 	//
 	// default mapping value: "0x101112" or "16 17 18" (first 3 channels, enabled, not inverted)
 	CHAR_DATA_AXIS_MAPPING = 0xFFD2
+
+	// sensor fusion (1 byte)
+	//
+	// format: 0b000MAAAA where
+	// - '0'    bit is not used,
+	// - 'M'    bit for magnetometer (9D fusion) enabled(1)/disabled(0), Nano 33 BLE only
+	// - 'AAAA' four bits for algorithm: 0 Madgwick (default), 1 Mahony, 2 Complementary filter
+	//
+	// examples:
+	// - 0x00 means Madgwick, 6D
+	// - 0x12 means Complementary filter, 9D
+	CHAR_DATA_FUSION = 0xFFD3
+
+	// board mounting (1 or 9 bytes)
+	//
+	// - first byte is right angle orientation index (0-23), see orientation.MountingRotation
+	// - optional 8 bytes are adjustment quaternion (w, x, y, z), int16 each, little endian, 16384 means 1.0
+	//
+	// examples:
+	// - "00" flat, USB-C facing right (default)
+	// - "02" flat, USB-C facing left
+	// - "00 213B 0000 0000 82E7" flat, USB-C facing right, board turned by 45 degrees counterclockwise
+	CHAR_DATA_MOUNTING = 0xFFD4
+
+	// response curves (18 bytes) - 6 bytes per axis
+	//
+	// each axis has format: deadband, expo, gain, endpoint low, endpoint high, trim where
+	// - deadband in 0.1 degree units,
+	// - expo in percent (0-100),
+	// - gain in degrees (1-180) of head rotation for full scale output,
+	// - endpoints in percent (0-100) of full scale, for negative and positive side,
+	// - trim in microseconds (int8), added to center.
+	//
+	// examples:
+	// - "00 00 B4 64 64 00" linear, ±180 degrees full scale (default)
+	// - "14 1E 5A 64 64 00" 2 degrees deadband, 30% expo, ±90 degrees full scale
+	CHAR_DATA_CURVES = 0xFFD5
+
+	// gimbal kinematics profile (12 bytes) - 4 bytes per axis
+	//
+	// each axis has format: throw, camera, servo, flags where
+	// - throw in degrees of servo for standard range (988-2012us), 0 disables the profile for the axis,
+	// - camera and servo are gear ratio, camera turns per servo turns,
+	// - flags has bit 0 for extended range (732-2268us).
+	//
+	// examples:
+	// - "00 00 00 00" profile disabled (default)
+	// - "5A 02 01 01" 90 degrees servo, 2:1 gear, extended range
+	CHAR_DATA_GIMBAL = 0xFFD6
+
+	// angles decomposition (1 byte)
+	//
+	// Euler angles order, outer to inner axis (Z pan, X tilt, Y roll), or swing-twist:
+	// 0 ZYX (default), 1 ZXY, 2 YXZ, 3 YZX, 4 XYZ, 5 XZY, 6 swing-twist
+	CHAR_DATA_ANGLES = 0xFFD7
+
+	// adaptive fusion gain (4 bytes)
+	//
+	// format: flags, still, fast, tolerance where
+	// - flags has bit 0 for enabled(1)/disabled(0),
+	// - still is gyroscope rate (dps) below which head is still, gain goes up,
+	// - fast is gyroscope rate (dps) above which head moves fast, gain goes down,
+	// - tolerance is acceleration deviation from 1g (percent) beyond which gravity is not trusted, gain goes down.
+	//
+	// examples:
+	// - "00 05 5A 0A" disabled (default)
+	// - "01 05 5A 0A" enabled, still below 5dps, fast above 90dps, 10% tolerance
+	CHAR_DATA_ADAPTIVE = 0xFFD8
+
+	// head gestures (5 bytes)
+	//
+	// format: nod, shake, left, right, spare where
+	// - nod, shake, left and right are actions for nod, shake, tilt hold left and tilt hold right gestures:
+	//   0 none (default), 1 re-center, 2 freeze output, 3 toggle gimbal profile, 4 flip spare channel,
+	// - spare is spare channel index (0-15), flipped between 1000 and 2000 by action 4.
+	//
+	// examples:
+	// - "00 00 00 00 03" no actions (default)
+	// - "01 02 00 04 03" nod re-centers, shake freezes output, tilt hold right flips channel 4
+	CHAR_DATA_GESTURES = 0xFFD9
+
+	// hold mode (2 bytes)
+	//
+	// format: mode, blend where
+	// - mode is what channels do while on hold: 0 freeze at current values (default), 1 center,
+	// - blend is time (in 100ms) channels take to reach hold values or return to live tracking, 0 means instant.
+	//
+	// hold is toggled with "H" command, long button press, triple tap or a head gesture
+	//
+	// examples:
+	// - "00 05" freeze, blend in 0.5s (default)
+	// - "01 0A" center, blend in 1s
+	CHAR_DATA_HOLD = 0xFFDA
+
+	// re-center duration (1 byte)
+	//
+	// time (in 100ms) output takes to move to new center on orientation reset, 0 means instant
+	//
+	// examples:
+	// - "05" 0.5s (default)
+	// - "00" instant
+	CHAR_DATA_RECENTER = 0xFFDB
+
+	// re-center modes (3 bytes)
+	//
+	// format: button, taps, command where each is 0 for full reset (default) or 1 for pan only:
+	// - button is reset orientation button,
+	// - taps is double tap and head gestures,
+	// - command is 'R' command, both Bluetooth and serial.
+	//
+	// full reset levels tilt and roll at current head position, pan only reset keeps them referenced to gravity
+	//
+	// examples:
+	// - "00 00 00" full reset everywhere (default)
+	// - "01 01 00" button and double tap reset pan only, command resets all
+	CHAR_DATA_RECENTER_MODES = 0xFFDC
+
+	// auto center (5 bytes)
+	//
+	// format: flags, still, time, window, rate where
+	// - flags has bit 0 for enabled(1)/disabled(0),
+	// - still is gyroscope rate (in 0.1 dps) below which head is still,
+	// - time is time (seconds) head shall stay still before centering starts,
+	// - window is pan angle (degrees) around center where centering works, looking further is never affected,
+	// - rate is centering speed (in 0.1 dps).
+	//
+	// examples:
+	// - "00 1E 05 0F 0A" disabled (default)
+	// - "01 1E 05 0F 0A" enabled, still below 3dps for 5s, within 15 degrees, at 1 dps
+	CHAR_DATA_AUTO_CENTER = 0xFFDD
+
+	// latency compensation (2 bytes)
+	//
+	// format: horizon, cap where
+	// - horizon is time (ms) output is extrapolated ahead along angular rate, 0 disables prediction,
+	// - cap is the largest extrapolation (degrees).
+	//
+	// examples:
+	// - "00 0A" disabled (default)
+	// - "28 0A" 40ms ahead, up to 10 degrees
+	CHAR_DATA_PREDICTION = 0xFFDE
+
+	// output smoothing filters (9 bytes) - 3 bytes per axis
+	//
+	// each axis has format: type, param1, param2 where type is
+	// - 0 none,
+	// - 1 One-Euro filter, param1 is min cutoff (0.1 Hz), param2 is beta (0.01 units),
+	// - 2 biquad low-pass filter, param1 is cutoff (0.1 Hz), param2 is Q (0.01 units).
+	//
+	// examples:
+	// - "01 0A 14" One-Euro filter, 1Hz min cutoff, beta 0.2 (default)
+	// - "02 32 47" biquad low-pass filter, 5Hz cutoff, Q 0.71
+	// - "00 00 00" no filter
+	CHAR_DATA_FILTERS = 0xFFDF
+
+	// continuous pan (3 bytes)
+	//
+	// format: mode, turns, rate where
+	// - mode is 0 for angle within ±180 degrees, 1 for multi-turn angle, 2 for rotation rate,
+	// - turns (1-255) is turns to full scale in multi-turn mode,
+	// - rate (10 dps units) is rate to full scale in rate mode.
+	//
+	// examples:
+	// - "00 02 24" angle within ±180 degrees (default)
+	// - "01 02 24" multi-turn, full scale at ±2 turns
+	// - "02 02 24" rate, full speed at 360 dps
+	CHAR_DATA_PAN = 0xFFE0
+
+	// PPM output (4 bytes), applies on next start in PPM mode
+	//
+	// format: channels, frame, spacer, polarity where
+	// - channels is number of channels in frame (1-16),
+	// - frame is frame length (0.5ms units), frame gets longer when channels leave less than 3ms for sync,
+	// - spacer is spacer length (10us units, 10-50),
+	// - polarity is 0 for line idle high and low spacers, 1 for inverted.
+	//
+	// examples:
+	// - "08 2D 1E 00" 8 channels, 22.5ms frame, 300us spacer (default)
+	// - "04 28 1E 01" 4 channels, 20ms frame, 300us spacer, inverted
+	CHAR_DATA_PPM = 0xFFE1
+
+	// wired output protocol (1 byte), applies on next start with wired output selected by pin
+	//
+	// protocol is one of:
+	// - 0 PPM on PPM pin (default),
+	// - 1 SBUS on UART TX pin,
+	// - 2 CRSF on UART TX pin,
+	// - 3 MAVLink on UART TX pin, see CHAR_DATA_MAVLINK,
+	// - 4 STorM32 on UART TX pin, see CHAR_DATA_CONTROLLER,
+	// - 5 SimpleBGC on UART TX pin, see CHAR_DATA_CONTROLLER.
+	//
+	// examples:
+	// - "00" PPM (default)
+	// - "01" SBUS
+	// - "02" CRSF
+	// - "03" MAVLink
+	// - "04" STorM32
+	// - "05" SimpleBGC
+	CHAR_DATA_WIRED = 0xFFE2
+
+	// MAVLink output (6 bytes), applies on next start with MAVLink wired output
+	//
+	// format: system, component, target system, target component, message, baud rate where
+	// - system and component are IDs of the head tracker,
+	// - target system and target component are IDs of the vehicle and of its autopilot (gimbal manager) or gimbal device,
+	// - message is 0 for GIMBAL_MANAGER_SET_ATTITUDE, 1 for GIMBAL_DEVICE_SET_ATTITUDE,
+	// - baud rate is in 9600 units.
+	//
+	// examples:
+	// - "FE 19 01 01 00 06" 254/25 to autopilot 1/1, gimbal manager, 57600 baud (default)
+	// - "FE 19 01 9A 01 0C" 254/25 to gimbal device 1/154, 115200 baud
+	CHAR_DATA_MAVLINK = 0xFFE3
+
+	// gimbal controller output (3 bytes), applies on next start with STorM32 or SimpleBGC wired output
+	//
+	// format: baud rate, inverted, speed where
+	// - baud rate is in 9600 units,
+	// - inverted has bit 0 for pitch, bit 1 for roll, bit 2 for yaw,
+	// - speed (10 dps units) is the largest speed to reach commanded angles, SimpleBGC only.
+	//
+	// examples:
+	// - "0C 00 1E" 115200 baud, not inverted, 300dps (default)
+	// - "0C 04 1E" 115200 baud, yaw inverted, 300dps
+	CHAR_DATA_CONTROLLER = 0xFFE4
 )
 
 // Have to send this to master radio on connect otherwise high chance opentx para code will never receive "Connected" message
@@ -56,7 +283,7 @@ type Para struct {
 	sendAfter time.Time
 
 	paired   bool
-	channels [8]uint16
+	channels [16]uint16 // as many as wired outputs carry, first 8 go over Bluetooth
 
 	remote ParaRemote
 }
@@ -66,13 +293,75 @@ type ParaRemote struct {
 	orientationReset bool
 	factoryReset     bool
 	reboot           bool
+	accCalibrate     bool
+	hold             bool
 
 	// remote configuration
-	nameChanged        bool
-	nameValue          [16]byte
-	nameLength         byte
-	axisMappingChanged bool
-	axisMappingValue   [3]byte
+	nameChanged          bool
+	nameValue            [16]byte
+	nameLength           byte
+	axisMappingChanged   bool
+	axisMappingValue     [3]byte
+	fusionChanged        bool
+	fusionValue          byte
+	mountingChanged      bool
+	mountingValue        [9]byte
+	curvesChanged        bool
+	curvesValue          [18]byte
+	gimbalChanged        bool
+	gimbalValue          [12]byte
+	anglesChanged        bool
+	anglesValue          byte
+	adaptiveChanged      bool
+	adaptiveValue        [4]byte
+	gesturesChanged      bool
+	gesturesValue        [5]byte
+	holdChanged          bool
+	holdValue            [2]byte
+	recenterChanged      bool
+	recenterValue        byte
+	recenterModesChanged bool
+	recenterModesValue   [3]byte
+	autoCenterChanged    bool
+	autoCenterValue      [5]byte
+	predictionChanged    bool
+	predictionValue      [2]byte
+	filtersChanged       bool
+	filtersValue         [9]byte
+	panChanged           bool
+	panValue             [3]byte
+	ppmChanged           bool
+	ppmValue             [4]byte
+	wiredChanged         bool
+	wiredValue           byte
+	mavlinkChanged       bool
+	mavlinkValue         [6]byte
+	controllerChanged    bool
+	controllerValue      [3]byte
+}
+
+// Persisted configuration, exposed for remote reading and editing
+type ParaSettings struct {
+	DeviceName    string
+	AxisMapping   [3]byte
+	Fusion        byte
+	Mounting      [9]byte
+	Curves        [18]byte
+	Gimbal        [12]byte
+	Angles        byte
+	Adaptive      [4]byte
+	Gestures      [5]byte
+	Hold          [2]byte
+	Recenter      byte
+	RecenterModes [3]byte
+	AutoCenter    [5]byte
+	Prediction    [2]byte
+	Filters       [9]byte
+	Pan           [3]byte
+	PPM           [4]byte
+	Wired         byte
+	Mavlink       [6]byte
+	Controller    [3]byte
 }
 
 type CallbackHandler interface {
@@ -84,25 +373,84 @@ type CallbackHandler interface {
 	OnOrientationReset()
 	OnReboot()
 	OnFactoryReset()
+	OnAccCalibrate()
+	OnHoldToggle()
 
 	// remote configuration
 	OnDeviceNameChange(name string)
 	OnAxisMappingChange(mapping [3]byte)
+	OnFusionChange(fusion byte)
+	OnMountingChange(mounting [9]byte)
+	OnCurvesChange(curves [18]byte)
+	OnGimbalChange(gimbal [12]byte)
+	OnAnglesChange(angles byte)
+	OnAdaptiveChange(adaptive [4]byte)
+	OnGesturesChange(gestures [5]byte)
+	OnHoldChange(hold [2]byte)
+	OnRecenterChange(recenter byte)
+	OnRecenterModesChange(recenterModes [3]byte)
+	OnAutoCenterChange(autoCenter [5]byte)
+	OnPredictionChange(prediction [2]byte)
+	OnFiltersChange(filters [9]byte)
+	OnPanChange(pan [3]byte)
+	OnPPMChange(ppm [4]byte)
+	OnWiredChange(wired byte)
+	OnMavlinkChange(mavlink [6]byte)
+	OnControllerChange(controller [3]byte)
 }
 
-func NewPara(name string, axisMapping [3]byte, callbackHandler CallbackHandler) *Para {
+func NewPara(settings ParaSettings, callbackHandler CallbackHandler) *Para {
+	name := settings.DeviceName
 	para := Para{
 		adapter:         bluetooth.DefaultAdapter,
 		callbackHandler: callbackHandler,
 		paired:          false,
-		channels:        [8]uint16{1500, 1500, 1500, 1500, 1500, 1500, 1500, 1500},
 		remote: ParaRemote{
-			nameChanged:        false,
-			nameLength:         byte(len(name)),
-			axisMappingChanged: false,
-			axisMappingValue:   axisMapping,
+			nameChanged:          false,
+			nameLength:           byte(len(name)),
+			axisMappingChanged:   false,
+			axisMappingValue:     settings.AxisMapping,
+			fusionChanged:        false,
+			fusionValue:          settings.Fusion,
+			mountingChanged:      false,
+			mountingValue:        settings.Mounting,
+			curvesChanged:        false,
+			curvesValue:          settings.Curves,
+			gimbalChanged:        false,
+			gimbalValue:          settings.Gimbal,
+			anglesChanged:        false,
+			anglesValue:          settings.Angles,
+			adaptiveChanged:      false,
+			adaptiveValue:        settings.Adaptive,
+			gesturesChanged:      false,
+			gesturesValue:        settings.Gestures,
+			holdChanged:          false,
+			holdValue:            settings.Hold,
+			recenterChanged:      false,
+			recenterValue:        settings.Recenter,
+			recenterModesChanged: false,
+			recenterModesValue:   settings.RecenterModes,
+			autoCenterChanged:    false,
+			autoCenterValue:      settings.AutoCenter,
+			predictionChanged:    false,
+			predictionValue:      settings.Prediction,
+			filtersChanged:       false,
+			filtersValue:         settings.Filters,
+			panChanged:           false,
+			panValue:             settings.Pan,
+			ppmChanged:           false,
+			ppmValue:             settings.PPM,
+			wiredChanged:         false,
+			wiredValue:           settings.Wired,
+			mavlinkChanged:       false,
+			mavlinkValue:         settings.Mavlink,
+			controllerChanged:    false,
+			controllerValue:      settings.Controller,
 		},
 	}
+	for i := range para.channels {
+		para.channels[i] = 1500
+	}
 	for i := 0; i < len(name) && i < 16; i++ {
 		para.remote.nameValue[i] = byte(name[i])
 	}
@@ -188,6 +536,8 @@ func (t *Para) Start() string {
 	// R - reset orientation, compatible with Cliff's HT reset button
 	// F - factory reset
 	// B - reboot device
+	// A - start accelerometer calibration
+	// H - toggle output hold
 	commandHandler := func(client bluetooth.Connection, offset int, value []byte) {
 		if len(value) == 1 && value[0] == CMD_ORIENTATION_RESET {
 			t.remote.orientationReset = true
@@ -198,6 +548,12 @@ func (t *Para) Start() string {
 		if len(value) == 1 && value[0] == CMD_REBOOT {
 			t.remote.reboot = true
 		}
+		if len(value) == 1 && value[0] == CMD_ACC_CALIBRATE {
+			t.remote.accCalibrate = true
+		}
+		if len(value) == 1 && value[0] == CMD_HOLD {
+			t.remote.hold = true
+		}
 	}
 	charCmd := bluetooth.CharacteristicConfig{
 		Handle:     nil,
@@ -244,12 +600,267 @@ func (t *Para) Start() string {
 				return
 			}
 			for i := 0; i < 3; i++ {
-				t.remote.axisMappingValue[i] = value[i] & 0b00110111 // mask out unused bits
+				t.remote.axisMappingValue[i] = value[i] & 0b00111111 // mask out unused bits
 			}
 			t.remote.axisMappingChanged = true
 		},
 	}
 
+	charFusion := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_FUSION),
+		Value:  []byte{t.remote.fusionValue},
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 1 {
+				return
+			}
+			t.remote.fusionValue = value[0] & 0b00011111 // mask out unused bits
+			t.remote.fusionChanged = true
+		},
+	}
+
+	charMounting := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_MOUNTING),
+		Value:  t.remote.mountingValue[:],
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 1 && len(value) != 9 {
+				return
+			}
+			t.remote.mountingValue = [9]byte{value[0], 0x00, 0x40} // no adjustment
+			copy(t.remote.mountingValue[1:], value[1:])
+			t.remote.mountingChanged = true
+		},
+	}
+
+	charCurves := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_CURVES),
+		Value:  t.remote.curvesValue[:],
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 18 {
+				return
+			}
+			copy(t.remote.curvesValue[:], value)
+			t.remote.curvesChanged = true
+		},
+	}
+
+	charGimbal := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_GIMBAL),
+		Value:  t.remote.gimbalValue[:],
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 12 {
+				return
+			}
+			copy(t.remote.gimbalValue[:], value)
+			t.remote.gimbalChanged = true
+		},
+	}
+
+	charAngles := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_ANGLES),
+		Value:  []byte{t.remote.anglesValue},
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 1 || value[0] > 6 {
+				return
+			}
+			t.remote.anglesValue = value[0]
+			t.remote.anglesChanged = true
+		},
+	}
+
+	charAdaptive := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_ADAPTIVE),
+		Value:  t.remote.adaptiveValue[:],
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 4 {
+				return
+			}
+			copy(t.remote.adaptiveValue[:], value)
+			t.remote.adaptiveValue[0] &= 0b00000001 // mask out unused bits
+			t.remote.adaptiveChanged = true
+		},
+	}
+
+	charGestures := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_GESTURES),
+		Value:  t.remote.gesturesValue[:],
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 5 || value[0] > 4 || value[1] > 4 || value[2] > 4 || value[3] > 4 || value[4] > 15 {
+				return
+			}
+			copy(t.remote.gesturesValue[:], value)
+			t.remote.gesturesChanged = true
+		},
+	}
+
+	charHold := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_HOLD),
+		Value:  t.remote.holdValue[:],
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 2 || value[0] > 1 {
+				return
+			}
+			copy(t.remote.holdValue[:], value)
+			t.remote.holdChanged = true
+		},
+	}
+
+	charRecenter := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_RECENTER),
+		Value:  []byte{t.remote.recenterValue},
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 1 {
+				return
+			}
+			t.remote.recenterValue = value[0]
+			t.remote.recenterChanged = true
+		},
+	}
+
+	charRecenterModes := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_RECENTER_MODES),
+		Value:  t.remote.recenterModesValue[:],
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 3 || value[0] > 1 || value[1] > 1 || value[2] > 1 {
+				return
+			}
+			copy(t.remote.recenterModesValue[:], value)
+			t.remote.recenterModesChanged = true
+		},
+	}
+
+	charAutoCenter := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_AUTO_CENTER),
+		Value:  t.remote.autoCenterValue[:],
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 5 {
+				return
+			}
+			copy(t.remote.autoCenterValue[:], value)
+			t.remote.autoCenterValue[0] &= 0b00000001 // mask out unused bits
+			t.remote.autoCenterChanged = true
+		},
+	}
+
+	charPrediction := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_PREDICTION),
+		Value:  t.remote.predictionValue[:],
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 2 {
+				return
+			}
+			copy(t.remote.predictionValue[:], value)
+			t.remote.predictionChanged = true
+		},
+	}
+
+	charFilters := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_FILTERS),
+		Value:  t.remote.filtersValue[:],
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 9 {
+				return
+			}
+			copy(t.remote.filtersValue[:], value)
+			t.remote.filtersChanged = true
+		},
+	}
+
+	charPan := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_PAN),
+		Value:  t.remote.panValue[:],
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 3 {
+				return
+			}
+			copy(t.remote.panValue[:], value)
+			t.remote.panChanged = true
+		},
+	}
+
+	charPPM := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_PPM),
+		Value:  t.remote.ppmValue[:],
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 4 {
+				return
+			}
+			copy(t.remote.ppmValue[:], value)
+			t.remote.ppmChanged = true
+		},
+	}
+
+	charWired := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_WIRED),
+		Value:  []byte{t.remote.wiredValue},
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 1 {
+				return
+			}
+			t.remote.wiredValue = value[0]
+			t.remote.wiredChanged = true
+		},
+	}
+
+	charMavlink := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_MAVLINK),
+		Value:  t.remote.mavlinkValue[:],
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 6 {
+				return
+			}
+			copy(t.remote.mavlinkValue[:], value)
+			t.remote.mavlinkChanged = true
+		},
+	}
+
+	charController := bluetooth.CharacteristicConfig{
+		Handle: nil,
+		UUID:   bluetooth.New16BitUUID(CHAR_DATA_CONTROLLER),
+		Value:  t.remote.controllerValue[:],
+		Flags:  bluetooth.CharacteristicReadPermission | bluetooth.CharacteristicWritePermission,
+		WriteEvent: func(client bluetooth.Connection, offset int, value []byte) {
+			if len(value) != 3 {
+				return
+			}
+			copy(t.remote.controllerValue[:], value)
+			t.remote.controllerChanged = true
+		},
+	}
+
 	t.adapter.AddService(&bluetooth.Service{
 		UUID: bluetooth.New16BitUUID(0xFFF0),
 		Characteristics: []bluetooth.CharacteristicConfig{
@@ -257,11 +868,29 @@ func (t *Para) Start() string {
 			fff2,
 			fff3,
 			fff5,
-			fff6,            // channels data
-			charCmd,         // runtime commands from client
-			charCmdCompat,   // for compatibility with Cliff's HT
-			charDeviceName,  // device name
-			charAxisMapping, // axis mapping
+			fff6,              // channels data
+			charCmd,           // runtime commands from client
+			charCmdCompat,     // for compatibility with Cliff's HT
+			charDeviceName,    // device name
+			charAxisMapping,   // axis mapping
+			charFusion,        // sensor fusion algorithm
+			charMounting,      // board mounting
+			charCurves,        // response curves
+			charGimbal,        // gimbal kinematics profile
+			charAngles,        // angles decomposition
+			charAdaptive,      // adaptive fusion gain
+			charGestures,      // head gestures
+			charHold,          // hold mode
+			charRecenter,      // re-center duration
+			charRecenterModes, // re-center modes
+			charAutoCenter,    // auto center
+			charPrediction,    // latency compensation
+			charFilters,       // output smoothing filters
+			charPan,           // continuous pan
+			charPPM,           // PPM output
+			charWired,         // wired output protocol
+			charMavlink,       // MAVLink output
+			charController,    // gimbal controller output
 		},
 	})
 
@@ -303,6 +932,14 @@ func (t *Para) Start() string {
 				t.remote.reboot = false
 				t.callbackHandler.OnReboot()
 			}
+			if t.remote.accCalibrate {
+				t.remote.accCalibrate = false
+				t.callbackHandler.OnAccCalibrate()
+			}
+			if t.remote.hold {
+				t.remote.hold = false
+				t.callbackHandler.OnHoldToggle()
+			}
 			if t.remote.nameChanged {
 				t.remote.nameChanged = false
 				nameBytes := t.remote.nameValue[:t.remote.nameLength]
@@ -319,6 +956,78 @@ func (t *Para) Start() string {
 				t.remote.axisMappingChanged = false
 				t.callbackHandler.OnAxisMappingChange(t.remote.axisMappingValue)
 			}
+			if t.remote.fusionChanged {
+				t.remote.fusionChanged = false
+				t.callbackHandler.OnFusionChange(t.remote.fusionValue)
+			}
+			if t.remote.mountingChanged {
+				t.remote.mountingChanged = false
+				t.callbackHandler.OnMountingChange(t.remote.mountingValue)
+			}
+			if t.remote.curvesChanged {
+				t.remote.curvesChanged = false
+				t.callbackHandler.OnCurvesChange(t.remote.curvesValue)
+			}
+			if t.remote.gimbalChanged {
+				t.remote.gimbalChanged = false
+				t.callbackHandler.OnGimbalChange(t.remote.gimbalValue)
+			}
+			if t.remote.anglesChanged {
+				t.remote.anglesChanged = false
+				t.callbackHandler.OnAnglesChange(t.remote.anglesValue)
+			}
+			if t.remote.adaptiveChanged {
+				t.remote.adaptiveChanged = false
+				t.callbackHandler.OnAdaptiveChange(t.remote.adaptiveValue)
+			}
+			if t.remote.gesturesChanged {
+				t.remote.gesturesChanged = false
+				t.callbackHandler.OnGesturesChange(t.remote.gesturesValue)
+			}
+			if t.remote.holdChanged {
+				t.remote.holdChanged = false
+				t.callbackHandler.OnHoldChange(t.remote.holdValue)
+			}
+			if t.remote.recenterChanged {
+				t.remote.recenterChanged = false
+				t.callbackHandler.OnRecenterChange(t.remote.recenterValue)
+			}
+			if t.remote.recenterModesChanged {
+				t.remote.recenterModesChanged = false
+				t.callbackHandler.OnRecenterModesChange(t.remote.recenterModesValue)
+			}
+			if t.remote.autoCenterChanged {
+				t.remote.autoCenterChanged = false
+				t.callbackHandler.OnAutoCenterChange(t.remote.autoCenterValue)
+			}
+			if t.remote.predictionChanged {
+				t.remote.predictionChanged = false
+				t.callbackHandler.OnPredictionChange(t.remote.predictionValue)
+			}
+			if t.remote.filtersChanged {
+				t.remote.filtersChanged = false
+				t.callbackHandler.OnFiltersChange(t.remote.filtersValue)
+			}
+			if t.remote.panChanged {
+				t.remote.panChanged = false
+				t.callbackHandler.OnPanChange(t.remote.panValue)
+			}
+			if t.remote.ppmChanged {
+				t.remote.ppmChanged = false
+				t.callbackHandler.OnPPMChange(t.remote.ppmValue)
+			}
+			if t.remote.wiredChanged {
+				t.remote.wiredChanged = false
+				t.callbackHandler.OnWiredChange(t.remote.wiredValue)
+			}
+			if t.remote.mavlinkChanged {
+				t.remote.mavlinkChanged = false
+				t.callbackHandler.OnMavlinkChange(t.remote.mavlinkValue)
+			}
+			if t.remote.controllerChanged {
+				t.remote.controllerChanged = false
+				t.callbackHandler.OnControllerChange(t.remote.controllerValue)
+			}
 		}
 	}()
 
@@ -342,6 +1051,9 @@ func (t *Para) update() {
 }
 
 func (p *Para) SetChannel(n int, v uint16) {
+	if n >= len(p.channels) {
+		return
+	}
 	p.channels[n] = v
 }
 
@@ -2,6 +2,18 @@ package trainer
 
 // PPM (Pulse-position modulation) wired trainer link
 // http://flyingeinstein.com/index.php/articles/58-ppm-explained
+//
+// Frame is channels, each is a pulse followed by a spacer, channel value is the time between spacers starts,
+// then sync pulse and a spacer until the end of frame.
+// Timer has one compare register for the next spacer, interrupt moves it on to the following one,
+// so number of channels is not limited by compare registers available.
+// Channel values are taken once per frame, at its last spacer, so a frame never mixes old and new values.
+//
+// Configuration has 4 bytes:
+// - channels  number of channels in frame (1-16),
+// - frame     frame length, 0.5ms units, frame gets longer when channels leave less than 3ms for sync,
+// - spacer    spacer length, 10us units (10-50),
+// - polarity  0 line idles high and spacers are low (default), 1 inverted.
 
 import (
 	"device/nrf"
@@ -11,20 +23,21 @@ import (
 	"unsafe"
 )
 
-const ppmFrameLength = 22500
-const ppmSpacerLength = 300
+const PPM_BYTES = 4
+
+const ppmMaxChannels = 16
+const ppmSyncMin = 3000 // us
 
 const (
-	ppmTimerLowEndOfChannelsIdx    = 3
-	ppmTimerLowEndOfFrameSpacerIdx = 4
-	ppmTimerLowEndOfFrameIdx       = 5
+	ppmTimerLowSpacerIdx     = 0 // next spacer start
+	ppmTimerLowEndOfFrameIdx = 1
 )
 const (
 	ppmUpdateInterruptId        = nrf.IRQ_TIMER3
-	ppmUpdateInterruptCondition = nrf.TIMER_INTENSET_COMPARE3
+	ppmUpdateInterruptCondition = nrf.TIMER_INTENSET_COMPARE0
 )
 const (
-	ppmTimerLowShorts  = nrf.TIMER_SHORTS_COMPARE5_CLEAR // 5th compare register is end of frame
+	ppmTimerLowShorts  = nrf.TIMER_SHORTS_COMPARE1_CLEAR // 1st compare register is end of frame
 	ppmTimerHighShorts = nrf.TIMER_SHORTS_COMPARE0_CLEAR | nrf.TIMER_SHORTS_COMPARE0_STOP
 )
 
@@ -40,19 +53,38 @@ var ppmInstance PPM
 
 type PPM struct {
 	pin      machine.Pin
-	channels [3]uint16
+	channels [ppmMaxChannels]uint16
+
+	count    int    // channels in frame
+	frame    uint32 // us
+	spacer   uint32 // us
+	inverted bool
+
+	spacers  [ppmMaxChannels + 1]uint32 // timer counts, spacers starts in current frame, the last is sync spacer
+	frameEnd uint32                     // timer count, end of the frame that starts next
+	next     int                        // index of spacer timer waits for
 }
 
-func NewPPM(pin machine.Pin) *PPM {
+func NewPPM(pin machine.Pin, config [PPM_BYTES]byte) *PPM {
 	ppmInstance = PPM{
 		pin:      pin,
-		channels: [3]uint16{1500, 1500, 1500},
+		count:    int(min(max(config[0], 1), ppmMaxChannels)),
+		frame:    uint32(config[1]) * 500,
+		spacer:   uint32(min(max(config[2], 10), 50)) * 10,
+		inverted: config[3]&0x01 == 0x01,
+	}
+	for i := range ppmInstance.channels {
+		ppmInstance.channels[i] = 1500
 	}
 	return &ppmInstance
 }
 
 func (ppm *PPM) Start() string {
-	ppm.pin.Low()
+	if ppm.inverted {
+		ppm.pin.High()
+	} else {
+		ppm.pin.Low()
+	}
 	configurePin()
 	configureTimers()
 	configurePpi()
@@ -63,42 +95,54 @@ func (ppm *PPM) Start() string {
 }
 
 func (ppm *PPM) SetChannel(n int, v uint16) {
+	if n >= ppm.count {
+		return
+	}
 	ppm.channels[n] = v
 }
 
+// Spacers of the next frame, from current channel values
+func (ppm *PPM) schedule() {
+	offset := uint32(0)
+	for i := 0; i < ppm.count; i++ {
+		offset += uint32(ppm.channels[i])
+		ppm.spacers[i] = microToCount(offset - ppm.spacer)
+	}
+	frame := max(ppm.frame, offset+ppmSyncMin)
+	ppm.spacers[ppm.count] = microToCount(frame - ppm.spacer)
+	ppm.frameEnd = microToCount(frame)
+}
+
 // --- Configure --------------------------------------------------------------
 
 func configurePin() {
+	outInit := uint32(nrf.GPIOTE_CONFIG_OUTINIT_Low)
+	if ppmInstance.inverted {
+		outInit = nrf.GPIOTE_CONFIG_OUTINIT_High
+	}
 	// Configure a GPIOTE channel.
 	nrf.GPIOTE.CONFIG[0].Set(
 		(nrf.GPIOTE_CONFIG_MODE_Task << nrf.GPIOTE_CONFIG_MODE_Pos) |
 			(uint32(ppmInstance.pin) << nrf.GPIOTE_CONFIG_PSEL_Pos) |
 			(nrf.GPIOTE_CONFIG_POLARITY_None << nrf.GPIOTE_CONFIG_POLARITY_Pos) |
-			(nrf.GPIOTE_CONFIG_OUTINIT_Low << nrf.GPIOTE_CONFIG_OUTINIT_Pos))
+			(outInit << nrf.GPIOTE_CONFIG_OUTINIT_Pos))
 }
 
 func configureTimers() {
 
-	// Timer that pulls pin low
+	// Timer that starts spacers
 	ppmTimerLow.TASKS_STOP.Set(1)
 	ppmTimerLow.PRESCALER.Set(ppmTimerPrescaler)
 	ppmTimerLow.SHORTS.Set(ppmTimerLowShorts)                // reset channel timer on frame's end
 	ppmTimerLow.BITMODE.Set(nrf.TIMER_BITMODE_BITMODE_32Bit) // low prescaler => more precision but large counters
 
-	// Spacers at the ends of channels
-	offset := uint16(0)
-	for i, v := range ppmInstance.channels {
-		offset += v
-		ppmTimerLow.CC[i].Set(microToCount(offset - ppmSpacerLength))
-	}
-	// All channels are sent => an interrupt fires that updates timer counter registers with new values
-	ppmTimerLow.CC[ppmTimerLowEndOfChannelsIdx].Set(microToCount(offset))
-	// Spacer at the end of frame
-	ppmTimerLow.CC[ppmTimerLowEndOfFrameSpacerIdx].Set(microToCount(ppmFrameLength - ppmSpacerLength))
-	// End of frame
-	ppmTimerLow.CC[ppmTimerLowEndOfFrameIdx].Set(microToCount(ppmFrameLength))
-
-	// Trigger update values handler
+	// First frame
+	ppmInstance.schedule()
+	ppmInstance.next = 0
+	ppmTimerLow.CC[ppmTimerLowSpacerIdx].Set(ppmInstance.spacers[0])
+	ppmTimerLow.CC[ppmTimerLowEndOfFrameIdx].Set(ppmInstance.frameEnd)
+
+	// Every spacer => an interrupt fires that moves compare register to the next spacer
 	ppmTimerLow.INTENSET.Set(ppmUpdateInterruptCondition)
 	itr := interrupt.New(ppmUpdateInterruptId, updateDelays)
 	itr.SetPriority(0x01)
@@ -106,31 +150,29 @@ func configureTimers() {
 
 	// ------------------------
 
-	// Timer that pulls pin high
+	// Timer that ends spacers
 	ppmTimerHigh.TASKS_STOP.Set(1)
 	ppmTimerHigh.PRESCALER.Set(ppmTimerPrescaler)
 	ppmTimerHigh.SHORTS.Set(ppmTimerHighShorts)               // reset and stop spacer timer automatically
 	ppmTimerHigh.BITMODE.Set(nrf.TIMER_BITMODE_BITMODE_32Bit) // low prescaler => more precision but large counters
 
 	// Spacer length
-	ppmTimerHigh.CC[0].Set(microToCount(ppmSpacerLength))
+	ppmTimerHigh.CC[0].Set(microToCount(ppmInstance.spacer))
 
 }
 
 func configurePpi() {
-
-	// Pull pin down after each channel and start spacer timer to pull it back up again
-	for i := range ppmInstance.channels {
-		configurePpiChannel(i*2, &ppmTimerLow.EVENTS_COMPARE[i], &nrf.GPIOTE.TASKS_CLR[0])
-		configurePpiChannel(i*2+1, &ppmTimerLow.EVENTS_COMPARE[i], &ppmTimerHigh.TASKS_START)
+	spacerStart, spacerEnd := &nrf.GPIOTE.TASKS_CLR[0], &nrf.GPIOTE.TASKS_SET[0]
+	if ppmInstance.inverted {
+		spacerStart, spacerEnd = spacerEnd, spacerStart
 	}
 
-	// Pull pin down near frame's end and start spacer timer to pull it back up again
-	configurePpiChannel(6, &ppmTimerLow.EVENTS_COMPARE[ppmTimerLowEndOfFrameSpacerIdx], &nrf.GPIOTE.TASKS_CLR[0])
-	configurePpiChannel(7, &ppmTimerLow.EVENTS_COMPARE[ppmTimerLowEndOfFrameSpacerIdx], &ppmTimerHigh.TASKS_START)
+	// Start spacer and spacer timer to end it
+	configurePpiChannel(0, &ppmTimerLow.EVENTS_COMPARE[ppmTimerLowSpacerIdx], spacerStart)
+	configurePpiChannel(1, &ppmTimerLow.EVENTS_COMPARE[ppmTimerLowSpacerIdx], &ppmTimerHigh.TASKS_START)
 
-	// Pull pin up on spacer timer event
-	configurePpiChannel(8, &ppmTimerHigh.EVENTS_COMPARE[0], &nrf.GPIOTE.TASKS_SET[0])
+	// End spacer on spacer timer event
+	configurePpiChannel(2, &ppmTimerHigh.EVENTS_COMPARE[0], spacerEnd)
 
 }
 
@@ -143,16 +185,23 @@ func configurePpiChannel(n int, event, task *volatile.Register32) {
 // --- Interrupt Handler ------------------------------------------------------
 
 func updateDelays(itr interrupt.Interrupt) {
-	if ppmTimerLow.EVENTS_COMPARE[ppmTimerLowEndOfChannelsIdx].Get() != 0 {
-		ppmTimerLow.EVENTS_COMPARE[ppmTimerLowEndOfChannelsIdx].Set(0)
-		offset := uint16(0)
-		for i, v := range ppmInstance.channels {
-			offset += v
-			ppmTimerLow.CC[i].Set(microToCount(offset - ppmSpacerLength))
-		}
+	if ppmTimerLow.EVENTS_COMPARE[ppmTimerLowSpacerIdx].Get() == 0 {
+		return
+	}
+	ppmTimerLow.EVENTS_COMPARE[ppmTimerLowSpacerIdx].Set(0)
+	ppm := &ppmInstance
+	if ppm.next == 0 { // frame has started, timer is past previous frame's end, safe to move it
+		ppmTimerLow.CC[ppmTimerLowEndOfFrameIdx].Set(ppm.frameEnd)
+	}
+	if ppm.next == ppm.count { // sync spacer, next is the first spacer of the next frame
+		ppm.schedule()
+		ppm.next = 0
+	} else {
+		ppm.next++
 	}
+	ppmTimerLow.CC[ppmTimerLowSpacerIdx].Set(ppm.spacers[ppm.next])
 }
 
-func microToCount(micro uint16) uint32 {
+func microToCount(micro uint32) uint32 {
 	return uint32(float64(micro) * ppmTimerCyclesPerMicrosecond)
 }
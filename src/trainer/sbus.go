@@ -0,0 +1,102 @@
+package trainer
+
+// SBUS wired trainer link, 100 kbaud 8E2 inverted, on UART TX pin, see "protocol.SbusEncode"
+//
+// nRF52 UART can not invert its line, so PWM drives the pin instead, one PWM period per bit (see "protocol.SbusLine").
+// EasyDMA feeds PWM with bit levels, a frame goes out on its own, CPU only starts it.
+// Standard SBUS inputs take the line as is, no inverter needed.
+// Frame lost and failsafe flags follow IMU health, see "SetHealth".
+
+import (
+	"device/nrf"
+	"machine"
+	"time"
+	"unsafe"
+
+	"github.com/ysoldak/HeadTracker/src/protocol"
+)
+
+const sbusFramePeriod = 14 * time.Millisecond
+
+const (
+	sbusPwmTop          = 16_000_000 / protocol.SBUS_BAUD_RATE // PWM clock cycles per bit
+	sbusPwmFallingEdge  = 0x8000                               // polarity bit of PWM value, line is high until compare value
+	sbusPwmHigh         = sbusPwmFallingEdge | sbusPwmTop      // high through the bit
+	sbusPwmLow          = sbusPwmFallingEdge                   // low through the bit, compare value is 0
+	sbusPwmSequenceBits = protocol.SBUS_LINE_BITS + 1          // and idle bit at the end, line stays low after sequence
+)
+
+var sbusPwm = nrf.PWM0
+
+type SBUS struct {
+	pin      machine.Pin
+	channels [protocol.SBUS_CHANNELS]uint16
+	flags    byte
+	frame    [protocol.SBUS_FRAME_BYTES]byte
+	sequence [sbusPwmSequenceBits]uint16 // PWM values, in RAM for EasyDMA
+}
+
+func NewSBUS(pin machine.Pin) *SBUS {
+	s := &SBUS{
+		pin: pin,
+	}
+	for i := range s.channels {
+		s.channels[i] = 1500
+	}
+	s.sequence[protocol.SBUS_LINE_BITS] = sbusPwmLow
+	return s
+}
+
+func (s *SBUS) Start() string {
+	// idle line is low, also when PWM is stopped
+	s.pin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	s.pin.Low()
+
+	sbusPwm.PSEL.OUT[0].Set(uint32(s.pin))
+	sbusPwm.ENABLE.Set(nrf.PWM_ENABLE_ENABLE_Enabled)
+	sbusPwm.MODE.Set(nrf.PWM_MODE_UPDOWN_Up)
+	sbusPwm.PRESCALER.Set(nrf.PWM_PRESCALER_PRESCALER_DIV_1)
+	sbusPwm.COUNTERTOP.Set(sbusPwmTop)
+	sbusPwm.DECODER.Set(nrf.PWM_DECODER_LOAD_Common<<nrf.PWM_DECODER_LOAD_Pos | nrf.PWM_DECODER_MODE_RefreshCount<<nrf.PWM_DECODER_MODE_Pos)
+	sbusPwm.LOOP.Set(0)
+	sbusPwm.SHORTS.Set(nrf.PWM_SHORTS_SEQEND0_STOP) // one frame per start
+	sbusPwm.SEQ[0].PTR.Set(uint32(uintptr(unsafe.Pointer(&s.sequence[0]))))
+	sbusPwm.SEQ[0].CNT.Set(sbusPwmSequenceBits)
+	sbusPwm.SEQ[0].REFRESH.Set(0) // every value lasts one period, one bit
+	sbusPwm.SEQ[0].ENDDELAY.Set(0)
+
+	go func() {
+		ticker := time.NewTicker(sbusFramePeriod)
+		started := false
+		for range ticker.C {
+			if started && sbusPwm.EVENTS_SEQEND[0].Get() == 0 { // previous frame still goes out, never happens at this period
+				continue
+			}
+			protocol.SbusEncode(&s.frame, &s.channels, s.flags)
+			protocol.SbusLine((*[protocol.SBUS_LINE_BITS]uint16)(s.sequence[:]), &s.frame, sbusPwmHigh, sbusPwmLow)
+			sbusPwm.EVENTS_SEQEND[0].Set(0)
+			sbusPwm.TASKS_SEQSTART[0].Set(1)
+			started = true
+		}
+	}()
+
+	return "   SBUS OUTPUT"
+}
+
+func (s *SBUS) SetChannel(n int, v uint16) {
+	if n >= len(s.channels) {
+		return
+	}
+	s.channels[n] = v
+}
+
+// Frame lost when IMU data is missing, failsafe when it is missing for long
+func (s *SBUS) SetHealth(lost, failsafe bool) {
+	s.flags = 0
+	if lost {
+		s.flags |= protocol.SbusFlagFrameLost
+	}
+	if failsafe {
+		s.flags |= protocol.SbusFlagFailsafe
+	}
+}
@@ -0,0 +1,173 @@
+// Evaluation of latency compensation (see orientation.Predictor) on recorded motion, runs on host:
+//
+//	go run ./tools/predict [-cap 10] [-horizons 0,20,40,60] [recording.txt]
+//
+// Record motion with "M" command in serial console (again to stop), save the output to a file.
+// Lines are "time (ms), quaternion (w, x, y, z), rates (x, y, z, dps)", other lines are skipped.
+// Without a recording, synthetic head motion is used.
+//
+// For every horizon, output predicted that far ahead is compared with actual orientation at that time,
+// errors (degrees) are printed next to errors of no prediction, that is of plain latency.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"github.com/ysoldak/HeadTracker/src/orientation"
+)
+
+type sample struct {
+	t     float64 // s
+	q     mgl.Quat
+	rates mgl.Vec3 // dps
+}
+
+func main() {
+	maxAngle := flag.Float64("cap", 10, "largest extrapolation, degrees")
+	horizons := flag.String("horizons", "0,10,20,30,40,50,60", "horizons to evaluate, ms")
+	flag.Parse()
+
+	var samples []sample
+	if flag.NArg() > 0 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		samples = parse(f)
+		f.Close()
+	} else {
+		fmt.Println("No recording, synthetic motion")
+		samples = synthetic(60, 0.02)
+	}
+	if len(samples) < 2 {
+		fmt.Fprintln(os.Stderr, "not enough samples")
+		os.Exit(1)
+	}
+	fmt.Printf("%d samples, %.1fs\n\n", len(samples), samples[len(samples)-1].t-samples[0].t)
+
+	fmt.Println("horizon ms | latency rms  max | predicted rms  max")
+	for _, h := range strings.Split(*horizons, ",") {
+		ms, err := strconv.Atoi(strings.TrimSpace(h))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		horizon := time.Duration(ms) * time.Millisecond
+		latRms, latMax := evaluate(samples, horizon, 0, *maxAngle)
+		preRms, preMax := evaluate(samples, horizon, horizon, *maxAngle)
+		fmt.Printf("%10d | %11.2f %4.1f | %13.2f %4.1f\n", ms, latRms, latMax, preRms, preMax)
+	}
+}
+
+// Errors (degrees) of output predicted "prediction" ahead against actual orientation "horizon" ahead
+func evaluate(samples []sample, horizon, prediction time.Duration, maxAngle float64) (rms, max float64) {
+	p := orientation.NewPredictor()
+	p.Horizon = prediction.Seconds()
+	p.MaxAngle = maxAngle
+	sum, n := 0.0, 0
+	for i, s := range samples {
+		dt := 0.0
+		if i > 0 {
+			dt = s.t - samples[i-1].t
+		}
+		predicted := p.Predict(s.q, s.rates, dt)
+		actual, ok := at(samples[i:], s.t+horizon.Seconds())
+		if !ok {
+			break
+		}
+		e := angle(predicted, actual)
+		sum += e * e
+		max = math.Max(max, e)
+		n++
+	}
+	return math.Sqrt(sum / float64(n)), max
+}
+
+// Orientation at time t, interpolated, samples start at or before t
+func at(samples []sample, t float64) (mgl.Quat, bool) {
+	for i := 1; i < len(samples); i++ {
+		if samples[i].t >= t {
+			a, b := samples[i-1], samples[i]
+			return mgl.QuatSlerp(a.q, b.q, (t-a.t)/(b.t-a.t)), true
+		}
+	}
+	return mgl.Quat{}, false
+}
+
+func angle(a, b mgl.Quat) float64 {
+	dot := math.Min(math.Abs(a.Normalize().Dot(b.Normalize())), 1)
+	return 2 * math.Acos(dot) * 180 / math.Pi
+}
+
+func parse(r io.Reader) (samples []sample) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ",")
+		if len(fields) != 8 {
+			continue
+		}
+		var v [8]float64
+		ok := true
+		for i, f := range fields {
+			var err error
+			v[i], err = strconv.ParseFloat(strings.TrimSpace(f), 64)
+			ok = ok && err == nil
+		}
+		if !ok {
+			continue
+		}
+		samples = append(samples, sample{
+			t:     v[0] / 1000,
+			q:     mgl.Quat{W: v[1], V: mgl.Vec3{v[2], v[3], v[4]}},
+			rates: mgl.Vec3{v[5], v[6], v[7]},
+		})
+	}
+	return samples
+}
+
+// Head looking around: minimum jerk moves of pan and tilt with pauses in between, rates as gyroscope would measure them
+func synthetic(duration, dt float64) (samples []sample) {
+	rnd := rand.New(rand.NewSource(1))
+	pan, tilt := 0.0, 0.0
+	var from, to [2]float64
+	start, length := 0.0, 0.0
+	prev := mgl.QuatIdent()
+	for t := 0.0; t < duration; t += dt {
+		if t >= start+length {
+			from = [2]float64{pan, tilt}
+			to = [2]float64{(rnd.Float64()*2 - 1) * 90, (rnd.Float64()*2 - 1) * 30}
+			start = t + rnd.Float64()*1.5 // pause
+			length = 0.3 + rnd.Float64()*0.6
+		}
+		if t >= start {
+			x := (t - start) / length
+			k := x * x * x * (10 - 15*x + 6*x*x)
+			pan = from[0] + (to[0]-from[0])*k
+			tilt = from[1] + (to[1]-from[1])*k
+		}
+		q := mgl.QuatRotate(pan*math.Pi/180, mgl.Vec3{0, 0, 1}).Mul(mgl.QuatRotate(tilt*math.Pi/180, mgl.Vec3{1, 0, 0}))
+		// rate in body frame, from rotation since previous sample
+		d := prev.Conjugate().Mul(q)
+		if d.W < 0 {
+			d = d.Scale(-1)
+		}
+		rates := mgl.Vec3{}
+		if n := d.V.Len(); n > 1e-12 {
+			rates = d.V.Mul(2 * math.Atan2(n, d.W) / n / dt * 180 / math.Pi)
+		}
+		samples = append(samples, sample{t: t, q: q, rates: rates})
+		prev = q
+	}
+	return samples
+}